@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"./sim"
+)
+
+// Reads any mix of results (our printed results JSON, or results store lines) and
+// replays, and reports how each bot did, overall and broken down by map size,
+// player count and seat.
+
+func analyse(files []string) {
+
+	var results []*GameResult
+
+	for _, filename := range files {
+
+		more, err := results_from_file(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			continue
+		}
+
+		results = append(results, more...)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No results found\n")
+		return
+	}
+
+	// Group every seat's result by bot, and by bot + category...
+
+	overall := make(map[string]*Tally)
+	by_size := make(map[string]map[string]*Tally)
+	by_players := make(map[string]map[string]*Tally)
+	by_seat := make(map[string]map[string]*Tally)
+
+	for _, result := range results {
+		for _, player := range result.Players {
+
+			if overall[player.Cmd] == nil {
+				overall[player.Cmd] = new(Tally)
+				by_size[player.Cmd] = make(map[string]*Tally)
+				by_players[player.Cmd] = make(map[string]*Tally)
+				by_seat[player.Cmd] = make(map[string]*Tally)
+			}
+
+			overall[player.Cmd].Add(player)
+			tally_for(by_size[player.Cmd], fmt.Sprintf("%dx%d", result.Width, result.Height)).Add(player)
+			tally_for(by_players[player.Cmd], fmt.Sprintf("%dp", len(result.Players))).Add(player)
+			tally_for(by_seat[player.Cmd], fmt.Sprintf("seat %d", player.Seat)).Add(player)
+		}
+	}
+
+	var bots []string
+	for bot := range overall {
+		bots = append(bots, bot)
+	}
+
+	sort.Slice(bots, func(a, b int) bool {
+		return overall[bots[a]].AvgRank() < overall[bots[b]].AvgRank()
+	})
+
+	fmt.Printf("%d games\n", len(results))
+
+	for _, bot := range bots {
+
+		t := overall[bot]
+
+		fmt.Printf("\n%s\n\n", bot)
+		fmt.Printf("    %-12s %s\n", "", tally_header())
+		fmt.Printf("    %-12s %s\n", "overall", t.String())
+
+		q := t.ScoreQuartiles()
+		fmt.Printf("    %-12s min %d / 25%% %d / median %d / 75%% %d / max %d (mean %.0f)\n", "scores", q[0], q[1], q[2], q[3], q[4], t.MeanScore())
+
+		for _, breakdown := range []map[string]*Tally{by_size[bot], by_players[bot], by_seat[bot]} {
+
+			var keys []string
+			for key := range breakdown {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			fmt.Printf("\n")
+
+			for _, key := range keys {
+				fmt.Printf("    %-12s %s\n", key, breakdown[key].String())
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------------------
+
+type Tally struct {
+	Ranks						[]int
+	Scores						[]int
+}
+
+func tally_for(m map[string]*Tally, key string) *Tally {
+	if m[key] == nil {
+		m[key] = new(Tally)
+	}
+	return m[key]
+}
+
+func (self *Tally) Add(player SeatResult) {
+	self.Ranks = append(self.Ranks, player.Rank)
+	self.Scores = append(self.Scores, player.Score)
+}
+
+func (self *Tally) Games() int {
+	return len(self.Ranks)
+}
+
+func (self *Tally) Wins() int {
+	wins := 0
+	for _, rank := range self.Ranks {
+		if rank == 1 {
+			wins++
+		}
+	}
+	return wins
+}
+
+func (self *Tally) AvgRank() float64 {
+	mean, _ := mean_and_margin(self.Ranks)
+	return mean
+}
+
+func (self *Tally) MeanScore() float64 {
+	mean, _ := mean_and_margin(self.Scores)
+	return mean
+}
+
+func (self *Tally) ScoreQuartiles() [5]int {
+
+	sorted := append([]int(nil), self.Scores...)
+	sort.Ints(sorted)
+
+	var ret [5]int
+	for i := 0; i < 5; i++ {
+		ret[i] = sorted[(len(sorted) - 1) * i / 4]
+	}
+	return ret
+}
+
+func tally_header() string {
+	return fmt.Sprintf("%6s  %-22s  %-18s  %s", "games", "win rate (95% CI)", "avg rank (95% CI)", "mean score")
+}
+
+func (self *Tally) String() string {
+
+	n := self.Games()
+	lo, hi := wilson_interval(self.Wins(), n)
+	rank, rank_margin := mean_and_margin(self.Ranks)
+
+	rank_string := fmt.Sprintf("%.2f ± %.2f", rank, rank_margin)
+	if math.IsInf(rank_margin, 1) {
+		rank_string = fmt.Sprintf("%.2f", rank)
+	}
+
+	return fmt.Sprintf("%6d  %-22s  %-18s  %.0f",
+		n,
+		fmt.Sprintf("%5.1f%% [%.1f, %.1f]", 100 * float64(self.Wins()) / float64(n), 100 * lo, 100 * hi),
+		rank_string,
+		self.MeanScore())
+}
+
+func wilson_interval(successes, n int) (float64, float64) {
+
+	// 95% Wilson score interval, which behaves itself near 0% and 100% (unlike the normal one).
+
+	if n == 0 {
+		return 0, 1
+	}
+
+	z := 1.96
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+
+	centre := (p + z * z / (2 * nf)) / (1 + z * z / nf)
+	margin := z * math.Sqrt(p * (1 - p) / nf + z * z / (4 * nf * nf)) / (1 + z * z / nf)
+
+	return centre - margin, centre + margin
+}
+
+func mean_and_margin(values []int) (float64, float64) {
+
+	// The mean, and the half-width of its 95% confidence interval (normal approximation).
+
+	n := float64(len(values))
+
+	if n == 0 {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / n
+
+	if n < 2 {
+		return mean, math.Inf(1)
+	}
+
+	ss := 0.0
+	for _, v := range values {
+		ss += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	return mean, 1.96 * math.Sqrt(ss / (n - 1) / n)
+}
+
+// -----------------------------------------------------------------------------------------
+
+func results_from_file(filename string) ([]*GameResult, error) {
+
+	if strings.HasSuffix(filename, ".hlt") {
+
+		replay, err := sim.ReplayFromFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := result_from_replay(replay)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*GameResult{result}, nil
+	}
+
+	// Otherwise it's a stream of JSON objects, either our printed results or results store lines.
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ret []*GameResult
+
+	dec := json.NewDecoder(f)
+
+	for {
+
+		var raw json.RawMessage
+
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]json.RawMessage
+		json.Unmarshal(raw, &fields)
+
+		if fields["players"] != nil {
+
+			result := new(GameResult)
+			err = json.Unmarshal(raw, result)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, result)
+
+		} else if fields["stats"] != nil {
+
+			ps := new(PrintedStats)
+			err = json.Unmarshal(raw, ps)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, result_from_printed_stats(ps))
+		}
+	}
+
+	return ret, nil
+}
+
+func result_from_printed_stats(ps *PrintedStats) *GameResult {
+
+	result := &GameResult{
+		Seed: ps.MapSeed,
+		Width: ps.MapWidth,
+		Height: ps.MapHeight,
+		Replay: ps.Replay,
+	}
+
+	for pid := 0; pid < len(ps.Stats); pid++ {
+
+		cmd := ps.Stats[pid].Cmd
+
+		if cmd == "" {
+			cmd = fmt.Sprintf("seat %d", pid)		// Official results don't say who the bots were
+		}
+
+		result.Players = append(result.Players, SeatResult{
+			Cmd: cmd,
+			Seat: pid,
+			Rank: ps.Stats[pid].Rank,
+			Score: ps.Stats[pid].Score,
+		})
+	}
+
+	return result
+}
+
+func result_from_replay(replay *sim.Replay) (*GameResult, error) {
+
+	// Replays don't have bot commands, so the bot's name stands in for them.
+
+	if replay.Stats == nil || replay.ProductionMap == nil {
+		return nil, fmt.Errorf("replay has no statistics")
+	}
+
+	result := &GameResult{
+		Seed: replay.Seed,
+		Width: replay.ProductionMap.Width,
+		Height: replay.ProductionMap.Height,
+	}
+
+	for _, pstats := range replay.Stats.Pstats {
+
+		name := ""
+		for _, player := range replay.Players {
+			if player.Pid == pstats.Pid {
+				name = player.Name
+			}
+		}
+
+		result.Players = append(result.Players, SeatResult{
+			Cmd: name,
+			Seat: pstats.Pid,
+			Rank: pstats.Rank,
+			Score: pstats.FinalProduction,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWilsonInterval(t *testing.T) {
+
+	tests := []struct {
+		successes, n	int
+		lo, hi			float64
+	}{
+		{5, 10, 0.2365895936, 0.7634104064},
+		{0, 10, 0, 0.2775401688},
+		{10, 10, 0.7224598312, 1},
+		{50, 100, 0.4038298286, 0.5961701714},
+		{1, 1, 0.2065432915, 1},
+		{0, 0, 0, 1},					// Knowing nothing
+	}
+
+	for _, test := range tests {
+		lo, hi := wilson_interval(test.successes, test.n)
+		if close_to(lo, test.lo) == false || close_to(hi, test.hi) == false {
+			t.Errorf("wilson_interval(%d, %d): got [%v, %v], expected [%v, %v]", test.successes, test.n, lo, hi, test.lo, test.hi)
+		}
+	}
+}
+
+func TestTally(t *testing.T) {
+
+	mean, margin := mean_and_margin([]int{1, 2, 3, 4})
+	if close_to(mean, 2.5) == false || close_to(margin, 1.2651745598) == false {
+		t.Errorf("mean_and_margin: got %v ± %v, expected 2.5 ± 1.2651745598", mean, margin)
+	}
+
+	if mean, margin = mean_and_margin([]int{3}); mean != 3 || math.IsInf(margin, 1) == false {
+		t.Errorf("mean_and_margin of 1 value: got %v ± %v", mean, margin)
+	}
+
+	tally := new(Tally)
+	for i, score := range []int{500, 100, 400, 200, 300} {
+		tally.Add(SeatResult{Rank: i % 2 + 1, Score: score})
+	}
+
+	if tally.Games() != 5 || tally.Wins() != 3 || close_to(tally.AvgRank(), 1.4) == false || tally.MeanScore() != 300 {
+		t.Errorf("Tally: got %d games, %d wins, avg rank %v, mean score %v", tally.Games(), tally.Wins(), tally.AvgRank(), tally.MeanScore())
+	}
+
+	if q := tally.ScoreQuartiles(); q != [5]int{100, 200, 300, 400, 500} {
+		t.Errorf("ScoreQuartiles: got %v", q)
+	}
+}
+
+func TestAnalyseReaders(t *testing.T) {
+
+	// A game's printed results, a results store line and its replay should all be read the same.
+
+	dir := t.TempDir()
+
+	out := run_dubnium(t, dir, "--seed", "3", "--width", "32", "--height", "32", "--replay-directory", dir, "builtin:greedy", "builtin:idle")
+
+	ps := new(PrintedStats)
+	if err := json.Unmarshal([]byte(out), ps); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	store_line := &GameResult{Seed: 3, Width: 32, Height: 32, Replay: ps.Replay, Players: []SeatResult{
+		{Cmd: "builtin:greedy", Seat: 0, Rank: ps.Stats[0].Rank, Score: ps.Stats[0].Score},
+		{Cmd: "builtin:idle", Seat: 1, Rank: ps.Stats[1].Rank, Score: ps.Stats[1].Score},
+	}}
+
+	j, _ := json.Marshal(store_line)
+
+	mixed := filepath.Join(dir, "mixed.json")
+	ioutil.WriteFile(mixed, []byte(out + "\n" + string(j) + "\n" + out), 0644)		// Any number of objects, of either kind
+
+	results, err := results_from_file(mixed)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("results_from_file: got %d results, %v", len(results), err)
+	}
+
+	for _, result := range results {
+		if reflect.DeepEqual(result, store_line) == false {
+			t.Errorf("Got %+v, expected %+v", result, store_line)
+		}
+	}
+
+	// The replay has bot names instead of commands, and no replay filename...
+
+	results, err = results_from_file(ps.Replay)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("results_from_file(%q): got %d results, %v", ps.Replay, len(results), err)
+	}
+
+	for pid, player := range results[0].Players {
+		expected := store_line.Players[pid]
+		if player.Seat != expected.Seat || player.Rank != expected.Rank || player.Score != expected.Score || player.Cmd == "" {
+			t.Errorf("From the replay, player %d was %+v, expected %+v", pid, player, expected)
+		}
+	}
+
+	if results[0].Seed != 3 || results[0].Width != 32 || results[0].Height != 32 {
+		t.Errorf("From the replay, got seed %d and size %dx%d", results[0].Seed, results[0].Width, results[0].Height)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+
+	"./hlt"
+	"./sim"
+)
+
+// Some simple bots that are always available, e.g. for smoke tests or as benchmark
+// opponents. A bot given as builtin:<name> is run by starting this executable again
+// with --builtin <name>, so as far as the rest of the engine is concerned it's just
+// another bot process.
+
+const BUILTIN_PREFIX = "builtin:"
+
+var builtin_bots = map[string]func(*hlt.Game) func() []sim.Move {
+	"idle":			idle_bot,
+	"random":		random_bot,
+	"greedy":		func(game *hlt.Game) func() []sim.Move { return miner_bot(game, false) },
+	"returner":		func(game *hlt.Game) func() []sim.Move { return miner_bot(game, true) },
+}
+
+func builtin_names() []string {
+	var ret []string
+	for name := range builtin_bots {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func bot_command_args(cmd string) []string {
+
+	// What to actually exec for a bot command.
+
+	if strings.HasPrefix(cmd, BUILTIN_PREFIX) {
+		executable, err := os.Executable()
+		if err != nil {
+			executable = os.Args[0]
+		}
+		return []string{executable, "--builtin", strings.TrimPrefix(cmd, BUILTIN_PREFIX)}
+	}
+
+	return strings.Fields(cmd)
+}
+
+func run_builtin(name string) {
+
+	maker, ok := builtin_bots[name]
+	if ok == false {
+		fmt.Fprintf(os.Stderr, "Unknown builtin bot \"%s\" (have: %s)\n", name, strings.Join(builtin_names(), ", "))
+		os.Exit(1)
+	}
+
+	game, err := hlt.NewGame(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	get_moves := maker(game)
+
+	game.Ready(name)
+
+	for game.Update() == nil {
+		game.Send(get_moves())
+	}
+}
+
+// -----------------------------------------------------------------------------------------
+
+func idle_bot(game *hlt.Game) func() []sim.Move {
+	return func() []sim.Move {
+		return nil
+	}
+}
+
+func random_bot(game *hlt.Game) func() []sim.Move {
+
+	// Random moves, but never an illegal one, i.e. it won't go over budget.
+
+	rng := rand.New(rand.NewSource(int64(game.Constants.GameSeed) + int64(game.Pid)))
+
+	return func() []sim.Move {
+
+		var moves []sim.Move
+
+		for _, ship := range game.Me().Ships {
+			moves = append(moves, hlt.MoveShip(ship.Sid, "nsewo"[rng.Intn(5):][:1]))
+		}
+
+		if game.Me().Budget >= game.Constants.NEW_ENTITY_ENERGY_COST && rng.Intn(10) == 0 {
+			moves = append(moves, hlt.Generate())
+		}
+
+		return moves
+	}
+}
+
+func miner_bot(game *hlt.Game, use_dropoffs bool) func() []sim.Move {
+
+	// Mines the best nearby cell, goes home when full, and avoids hitting its own
+	// ships (mostly). With use_dropoffs it also builds dropoffs far from home, and
+	// returns to the nearest structure rather than the factory.
+
+	returning := make(map[int]bool)		// sid --> heading home
+
+	return func() []sim.Move {
+
+		me := game.Me()
+		c := game.Constants
+
+		homes := []sim.Dropoff{me.Factory}
+		if use_dropoffs {
+			homes = append(homes, me.Dropoffs...)
+		}
+
+		nearest_home := func(x, y int) sim.Dropoff {
+			best := homes[0]
+			for _, home := range homes[1:] {
+				if game.Distance(x, y, home.X, home.Y) < game.Distance(x, y, best.X, best.Y) {
+					best = home
+				}
+			}
+			return best
+		}
+
+		var moves []sim.Move
+		claimed := make(map[sim.Position]bool)
+		budget := me.Budget
+		turns_left := c.MAX_TURNS - game.Turn
+
+		// Ships that can't move go first, since their cells are taken regardless.
+
+		var movers []sim.Ship
+
+		for _, ship := range me.Ships {
+			if ship.Halite < game.MoveCost(ship) {
+				claimed[sim.Position{X: ship.X, Y: ship.Y}] = true
+			} else {
+				movers = append(movers, ship)
+			}
+		}
+
+		built := false
+
+		for _, ship := range movers {
+
+			home := nearest_home(ship.X, ship.Y)
+			distance := game.Distance(ship.X, ship.Y, home.X, home.Y)
+
+			if ship.X == home.X && ship.Y == home.Y {
+				returning[ship.Sid] = false
+			} else if ship.Halite >= c.MAX_ENERGY * 9 / 10 || turns_left <= distance + 5 {
+				returning[ship.Sid] = true
+			}
+
+			// Maybe make a dropoff...
+
+			if use_dropoffs && built == false && returning[ship.Sid] == false && turns_left > 100 && len(me.Dropoffs) < 3 {
+
+				_, occupied := game.StructureAt(ship.X, ship.Y)
+
+				if occupied == false && distance >= 12 && len(me.Ships) >= 10 &&
+						halite_near(game, ship.X, ship.Y, 4) >= c.DROPOFF_COST * 3 &&
+						budget + ship.Halite + game.HaliteAt(ship.X, ship.Y) >= c.DROPOFF_COST {
+					moves = append(moves, hlt.Construct(ship.Sid))
+					budget -= c.DROPOFF_COST - ship.Halite - game.HaliteAt(ship.X, ship.Y)
+					built = true
+					continue
+				}
+			}
+
+			// Decide where we'd like to be, best first...
+
+			var wanted []string
+
+			if returning[ship.Sid] {
+
+				wanted = game.DirectionsAround(ship.X, ship.Y, home.X, home.Y)
+
+				// At the very end, pile into the structure regardless of collisions.
+
+				if turns_left <= 2 && distance == 1 && len(wanted) > 0 {
+					moves = append(moves, hlt.MoveShip(ship.Sid, wanted[0]))
+					continue
+				}
+
+			} else {
+
+				here := game.HaliteAt(ship.X, ship.Y)
+
+				if here < c.MAX_CELL_PRODUCTION / 10 {		// i.e. not worth staying
+					wanted = richest_neighbours(game, ship)
+				}
+			}
+
+			wanted = append(wanted, "o")
+
+			chosen := ""
+
+			for _, direction := range wanted {
+				x, y := game.Step(ship.X, ship.Y, direction)
+				if direction != "o" && x == ship.X && y == ship.Y {
+					continue						// Blocked by an obstacle or the edge
+				}
+				if claimed[sim.Position{X: x, Y: y}] == false {
+					chosen = direction
+					break
+				}
+			}
+
+			if chosen == "" {
+				chosen = "o"						// Nowhere safe; a collision might happen
+			}
+
+			x, y := game.Step(ship.X, ship.Y, chosen)
+			claimed[sim.Position{X: x, Y: y}] = true
+
+			moves = append(moves, hlt.MoveShip(ship.Sid, chosen))
+		}
+
+		factory := sim.Position{X: me.Factory.X, Y: me.Factory.Y}
+
+		if budget >= c.NEW_ENTITY_ENERGY_COST && game.Turn < c.MAX_TURNS / 2 && claimed[factory] == false {
+			moves = append(moves, hlt.Generate())
+		}
+
+		return moves
+	}
+}
+
+func richest_neighbours(game *hlt.Game, ship sim.Ship) []string {
+
+	directions := append([]string(nil), hlt.Directions...)
+
+	sort.SliceStable(directions, func(a, b int) bool {
+		xa, ya := game.Step(ship.X, ship.Y, directions[a])
+		xb, yb := game.Step(ship.X, ship.Y, directions[b])
+		return game.HaliteAt(xa, ya) > game.HaliteAt(xb, yb)
+	})
+
+	return directions
+}
+
+func halite_near(game *hlt.Game, x, y, radius int) int {
+	total := 0
+	for dx := -radius; dx <= radius; dx++ {
+		for dy := -radius; dy <= radius; dy++ {
+			if game.Distance(x, y, x + dx, y + dy) <= radius && game.IsObstacle(x + dx, y + dy) == false {
+				total += game.HaliteAt(x + dx, y + dy)
+			}
+		}
+	}
+	return total
+}
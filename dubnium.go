@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -24,20 +26,15 @@ type BotOutput struct {
 var bot_output_chan = make(chan BotOutput)		// Shared by all bot handlers.
 
 var all_stdin_pipes []io.WriteCloser
+var bot_logs = make(map[int][]string)				// pid --> bot's stderr, and our complaints about it
 var all_running_processes []*exec.Cmd
 var MUTEX sync.Mutex
 
 // -----------------------------------------------------------------------------------------
 
-func bot_handler(cmd string, pid int, io chan string, pregame string) {
+func bot_handler(cmd string, pid int, io chan string, pregame string, transcript *Transcript, adapter *JSONAdapter) {
 
-	// There are 2 clear places where this handler can hang: the 2 Scan() calls.
-	// Therefore it is essential that main() never try to send to the io channel
-	// unless it knows that those scans succeeded.
-
-	bot_is_kill := false
-
-	cmd_split := strings.Fields(cmd)
+	cmd_split := bot_command_args(cmd)
 	exec_command := exec.Command(cmd_split[0], cmd_split[1:]...)
 
 	// Note that the command isn't run until we call Start().
@@ -50,51 +47,127 @@ func bot_handler(cmd string, pid int, io chan string, pregame string) {
 	err := exec_command.Start()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start bot %d (%s)\n", pid, cmd)
-		bot_is_kill = true
-	} else {
-		MUTEX.Lock()
-		all_running_processes = append(all_running_processes, exec_command)
-		all_stdin_pipes = append(all_stdin_pipes, i_pipe)
-		MUTEX.Unlock()
+		bot_output_chan <- BotOutput{pid, "Non-starter (exec)"}
+		for {
+			<- io			// Nothing, just let it time out
+		}
+	}
+
+	MUTEX.Lock()
+	all_running_processes = append(all_running_processes, exec_command)
+	all_stdin_pipes = append(all_stdin_pipes, i_pipe)
+	MUTEX.Unlock()
+
+	go pipe_to_stderr(e_pipe, pid)
+
+	stream_handler(pid, o_pipe, i_pipe, io, pregame, transcript, adapter)
+}
+
+func remote_handler(conn net.Conn, pid int, io chan string, pregame string, transcript *Transcript, adapter *JSONAdapter) {
+
+	// A bot that connected to us (see --listen) rather than being started by us.
+
+	MUTEX.Lock()
+	all_stdin_pipes = append(all_stdin_pipes, conn)			// So it gets closed at the end
+	MUTEX.Unlock()
+
+	stream_handler(pid, conn, conn, io, pregame, transcript, adapter)
+}
+
+func stream_handler(pid int, bot_out io.Reader, bot_in io.Writer, io_chan chan string, pregame string, transcript *Transcript, adapter *JSONAdapter) {
+
+	// Speaks the protocol with a bot, however we're connected to it. If the adapter
+	// isn't nil, it's the JSON version of the protocol.
+	//
+	// There are 2 clear places where this handler can hang: the 2 Scan() calls.
+	// Therefore it is essential that main() never try to send to the io channel
+	// unless it knows that those scans succeeded.
+	//
+	// The transcript may be nil, in which case nothing is recorded.
+
+	bot_is_kill := false
+
+	stdin := transcript.Wrap(bot_in)
+
+	if adapter != nil {
+		translated, err := adapter.Pregame(pregame)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Bot %d: %v\n", pid, err)
+			bot_log(pid, fmt.Sprintf("Couldn't make the JSON init: %v", err))
+			bot_is_kill = true
+		}
+		pregame = translated
 	}
 
 	if bot_is_kill == false {
-		go pipe_to_stderr(e_pipe, pid)
-		fmt.Fprintf(i_pipe, pregame)
-		if pregame[len(pregame) - 1] != '\n' {
-			fmt.Fprintf(i_pipe, "\n")
+		fmt.Fprintf(stdin, "%s", pregame)
+		if len(pregame) == 0 || pregame[len(pregame) - 1] != '\n' {
+			fmt.Fprintf(stdin, "\n")
 		}
 	}
 
-	scanner := bufio.NewScanner(o_pipe)
+	scanner := bufio.NewScanner(bot_out)
 
-	if bot_is_kill == false && scanner.Scan() == false {				// So the Scan() happens if bot at least started.
+	if bot_is_kill {
+		bot_output_chan <- BotOutput{pid, "Non-starter (bad init)"}
+	} else if scanner.Scan() == false {
 		fmt.Fprintf(os.Stderr, "Bot %d output reached EOF\n", pid)
+		bot_log(pid, "Output reached EOF.")
 		bot_output_chan <- BotOutput{pid, "Non-starter (EOF)"}
 		bot_is_kill = true
-	} else if bot_is_kill {
-		bot_output_chan <- BotOutput{pid, "Non-starter (exec)"}
 	} else {
-		bot_output_chan <- BotOutput{pid, scanner.Text()}
+		name := scanner.Text()
+		if adapter != nil {
+			name = adapter.Name(name)
+		}
+		transcript.Record(0, name)
+		bot_output_chan <- BotOutput{pid, name}
 	}
 
 	for {
 
-		to_send := <- io			// Since this blocks, main() must never send via io unless it knows our last Scan() worked.
+		to_send := <- io_chan		// Since this blocks, main() must never send via io unless it knows our last Scan() worked.
 
 		if bot_is_kill == false {
 
-			fmt.Fprintf(i_pipe, to_send)
-			if to_send[len(to_send) - 1] != '\n' {
-				fmt.Fprintf(i_pipe, "\n")
+			turn := turn_from_update_string(to_send)
+
+			if adapter != nil {
+				translated, err := adapter.Update(to_send)
+				if err != nil {								// Can't tell the bot what happened, so kick it
+					fmt.Fprintf(os.Stderr, "Bot %d: %v\n", pid, err)
+					bot_log(pid, fmt.Sprintf("Couldn't make the JSON update: %v", err))
+					bot_is_kill = true
+					bot_output_chan <- BotOutput{pid, JSON_REJECTED}
+					continue
+				}
+				to_send = translated
+			}
+
+			fmt.Fprintf(stdin, "%s", to_send)
+			if len(to_send) == 0 || to_send[len(to_send) - 1] != '\n' {
+				fmt.Fprintf(stdin, "\n")
 			}
 
+			moves := ""
+
 			if scanner.Scan() == false {
 				fmt.Fprintf(os.Stderr, "Bot %d output reached EOF\n", pid)
+				bot_log(pid, "Output reached EOF.")
 				bot_is_kill = true
+			} else {
+				moves = scanner.Text()
+				if adapter != nil {
+					var error_lines []string
+					moves, error_lines = adapter.Moves(moves)
+					for _, line := range error_lines {
+						fmt.Fprintf(stdin, "%s\n", line)
+					}
+				}
+				transcript.Record(turn, moves)
 			}
 
-			bot_output_chan <- BotOutput{pid, scanner.Text()}
+			bot_output_chan <- BotOutput{pid, moves}
 
 		} else {
 
@@ -108,24 +181,280 @@ func pipe_to_stderr(p io.ReadCloser, pid int) {
 	scanner := bufio.NewScanner(p)
 	for scanner.Scan() {
 		fmt.Fprintf(os.Stderr, "Bot %v: %v\n", pid, scanner.Text())
+		bot_log(pid, scanner.Text())
 	}
 }
 
+func bot_log(pid int, line string) {
+
+	// Keeps what we know about a bot's troubles, for its error log (if it gets one).
+
+	MUTEX.Lock()
+	defer MUTEX.Unlock()
+
+	bot_logs[pid] = append(bot_logs[pid], line)
+}
+
+func write_error_log(filename string, pid int) error {
+
+	MUTEX.Lock()
+	lines := append([]string(nil), bot_logs[pid]...)
+	MUTEX.Unlock()
+
+	lines = append(lines, fmt.Sprintf("Player %d was kicked.", pid))
+
+	return ioutil.WriteFile(filename, []byte(strings.Join(lines, "\n") + "\n"), 0644)
+}
+
+// -----------------------------------------------------------------------------------------
+
+type TranscriptLine struct {
+	Turn					int			`json:"turn"`
+	Line					string		`json:"line"`
+	Timeout					bool		`json:"timeout,omitempty"`		// The engine gave up waiting (the line is then always "")
+}
+
+type Transcript struct {
+	in						*os.File	// Exact bytes the bot received on stdin
+	out						*os.File	// Lines the bot sent back, as JSON with turn numbers
+	mutex					sync.Mutex	// Lines come from the handler, timeouts from main()
+}
+
+func NewTranscript(base string, pid int) *Transcript {
+
+	in, err := os.Create(fmt.Sprintf("%s-p%d.in", base, pid))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return nil
+	}
+
+	out, err := os.Create(fmt.Sprintf("%s-p%d.out", base, pid))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		in.Close()
+		return nil
+	}
+
+	return &Transcript{in: in, out: out}
+}
+
+func (self *Transcript) Wrap(w io.Writer) io.Writer {
+	if self == nil {
+		return w
+	}
+	return io.MultiWriter(self.in, w)		// Record first, so a write to a dead bot is still logged
+}
+
+func (self *Transcript) Record(turn int, line string) {
+	self.write(TranscriptLine{Turn: turn, Line: line})
+}
+
+func (self *Transcript) RecordTimeout(turn int) {
+	self.write(TranscriptLine{Turn: turn, Timeout: true})
+}
+
+func (self *Transcript) write(tl TranscriptLine) {
+
+	if self == nil {
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	j, _ := json.Marshal(tl)
+	self.out.Write(j)
+	self.out.Write([]byte{'\n'})
+}
+
+func (self *Transcript) Close() {
+	if self == nil {
+		return
+	}
+	self.in.Close()
+	self.out.Close()
+}
+
+type Recording struct {								// A transcript .out file, loaded for playback
+	Lines					map[int]string
+	Timeouts				map[int]bool
+}
+
+func LoadRecording(filename string) (*Recording, error) {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	self := &Recording{
+		Lines: make(map[int]string),
+		Timeouts: make(map[int]bool),
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	for scanner.Scan() {
+
+		var tl TranscriptLine
+
+		err := json.Unmarshal(scanner.Bytes(), &tl)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		if tl.Timeout {
+			self.Timeouts[tl.Turn] = true
+		} else {
+			self.Lines[tl.Turn] = tl.Line
+		}
+	}
+
+	return self, scanner.Err()
+}
+
+func playback_pregame(filename string) (*sim.Frame, *sim.Constants, error) {
+
+	// The pregame at the start of a transcript, which is JSON if the bot was a json: one.
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	if scanner.Scan() {
+		if pregame, ok := PregameFromJSONInit(scanner.Text()); ok {
+			return sim.FrameFromPregameString(pregame)
+		}
+	}
+
+	return sim.FrameFromPregame(filename)
+}
+
+func turn_from_update_string(s string) int {
+
+	// The first line of every update is the turn number.
+
+	n, _ := strconv.Atoi(strings.SplitN(s, "\n", 2)[0])
+	return n
+}
+
 // -----------------------------------------------------------------------------------------
 
 func main() {
 
 	start_time := time.Now()
 
-	// This stuff should be a struct I guess...
-	width, height, sleep, seed, no_timeout, no_replay, viewer, folder, infile, inPNG, botlist := parse_args()
+	opts := parse_args()
+
+	if opts.Builtin != "" {
+		run_builtin(opts.Builtin)
+		return
+	}
+
+	if opts.BotInputReplay != "" {
+		print_bot_input(opts.BotInputReplay, opts.BotInputPid)
+		return
+	}
+
+	if opts.Tournament != "" {
+		tournament(opts)
+		return
+	}
+
+	if opts.Ratings != "" {
+		print_ratings(opts.Ratings)
+		return
+	}
+
+	if opts.SPRT {
+		sprt(opts)
+		return
+	}
+
+	if opts.Analyse {
+		analyse(opts.Botlist)			// Which are really filenames in this case
+		return
+	}
+
+	width, height, seed := opts.Width, opts.Height, opts.Seed		// Can be changed by a provided frame
 
 	var provided_frame *sim.Frame
+	var provided_constants *sim.Constants		// Only from playback
+	var recordings []*Recording					// Likewise
+
+	if opts.Infile != "" {
+		provided_frame, seed = sim.FrameFromFile(opts.Infile)
+	} else if opts.InPNG != "" {
+		provided_frame = sim.FrameFromPNG(opts.InPNG)
+	} else if opts.Snapshot != "" {
 
-	if infile != "" {
-		provided_frame, seed = sim.FrameFromFile(infile)
-	} else if inPNG != "" {
-		provided_frame = sim.FrameFromPNG(inPNG)
+		snapshot := opts.Snapshot
+
+		if strings.HasPrefix(snapshot, "@") {					// Read from a file instead
+			b, err := ioutil.ReadFile(snapshot[1:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return
+			}
+			snapshot = string(b)
+		}
+
+		var err error
+		provided_frame, err = sim.FrameFromSnapshot(snapshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+
+	} else if opts.Playback != "" {
+
+		if len(opts.Botlist) > 0 {
+			fmt.Fprintf(os.Stderr, "Bots can't be given in playback mode\n")
+			return
+		}
+
+		var err error
+		provided_frame, provided_constants, err = playback_pregame(opts.Playback + "-p0.in")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+
+		seed = provided_constants.GameSeed
+
+		for pid := 0; pid < provided_frame.Players(); pid++ {
+			filename := fmt.Sprintf("%s-p%d.out", opts.Playback, pid)
+			recording, err := LoadRecording(filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return
+			}
+			recordings = append(recordings, recording)
+
+			if cmd, ok := opts.Live[pid]; ok {
+				opts.Botlist = append(opts.Botlist, cmd)
+			} else {
+				opts.Botlist = append(opts.Botlist, "playback:" + filename)
+			}
+		}
+
+		for pid := range opts.Live {
+			if pid < 0 || pid >= provided_frame.Players() {
+				fmt.Fprintf(os.Stderr, "Bad pid %d for --live\n", pid)
+				return
+			}
+		}
+
+	} else if len(opts.Live) > 0 {
+		fmt.Fprintf(os.Stderr, "--live only makes sense with --playback\n")
+		return
 	}
 
 	if provided_frame != nil {
@@ -135,7 +464,76 @@ func main() {
 
 	turns := turns_from_size(width, height)
 
-	players := len(botlist)
+	if provided_constants != nil {
+		turns = provided_constants.MAX_TURNS
+	}
+
+	players := len(opts.Botlist)
+
+	humans, remotes := 0, 0
+
+	for _, cmd := range opts.Botlist {
+		seat := parse_seat(cmd)
+		if seat.Cmd == HUMAN_BOT && (seat.JSON || seat.Extended) {
+			fmt.Fprintf(os.Stderr, "The human player only speaks the plain protocol: %s\n", cmd)
+			return
+		}
+		cmd = seat.Cmd
+		if cmd == HUMAN_BOT {
+			humans++
+		}
+		if cmd == REMOTE_BOT {
+			remotes++
+		}
+	}
+
+	if (remotes > 0) != (opts.Listen != "") {
+		fmt.Fprintf(os.Stderr, "--listen and remote bots must be used together\n")
+		return
+	}
+
+	if humans > 1 {
+		fmt.Fprintf(os.Stderr, "Only 1 human player can share the terminal\n")
+		return
+	}
+
+	if humans > 0 && opts.NoTimeout == false {
+		fmt.Fprintf(os.Stderr, "Warning: the human player has the usual time limit. You probably want --no-timeout.\n")
+	}
+
+	for _, cmd := range opts.Botlist {
+		seat := parse_seat(cmd)
+		if strings.HasPrefix(seat.Cmd, BUILTIN_PREFIX) == false {
+			continue
+		}
+		if builtin_bots[strings.TrimPrefix(seat.Cmd, BUILTIN_PREFIX)] == nil {
+			fmt.Fprintf(os.Stderr, "Unknown builtin bot \"%s\" (have: %s)\n", seat.Cmd, strings.Join(builtin_names(), ", "))
+			return
+		}
+		if seat.JSON || seat.Extended {
+			fmt.Fprintf(os.Stderr, "Builtin bots only speak the plain protocol: %s\n", cmd)
+			return
+		}
+	}
+
+	if recordings == nil {
+		recordings = make([]*Recording, players)				// i.e. all nil, every seat is a live bot
+	}
+
+	// A seat can have a recording, a bot, or (when branching) both, in which case
+	// the bot is fed every update but its moves are only used from the branch turn.
+
+	bots := make([]bool, players)
+
+	for pid := 0; pid < players; pid++ {
+		_, live := opts.Live[pid]
+		bots[pid] = recordings[pid] == nil || live
+	}
+
+	if provided_frame != nil && provided_frame.Turn() >= turns {
+		fmt.Fprintf(os.Stderr, "Snapshot is at turn %d but the game only lasts %d turns\n", provided_frame.Turn(), turns)
+		return
+	}
 
 	if provided_frame != nil && provided_frame.Players() != players {
 		fmt.Fprintf(os.Stderr, "Wrong number of bots (%d) given for this replay (need %d)\n", players, provided_frame.Players())
@@ -154,30 +552,111 @@ func main() {
 	}
 
 	constants := sim.NewConstants(players, width, height, turns, seed)
+
+	if provided_constants != nil {
+		constants = provided_constants
+	}
+
+	if opts.Rules != "" && opts.Rules != "official" {
+		constants.RULES = opts.Rules
+	}
+
+	if opts.Bounded {
+		constants.BOUNDED = true
+	}
+
+	if opts.Fog > 0 {
+		constants.FOG_RADIUS = opts.Fog
+	}
+
+	if opts.Regrowth > 0 {
+		constants.REGROWTH_RATIO = opts.Regrowth
+		constants.REGROWTH_TARGET = opts.RegrowthTarget
+	}
+
+	if constants.FOG_RADIUS > 0 {
+		for _, cmd := range opts.Botlist {
+			if parse_seat(cmd).Extended {
+				fmt.Fprintf(os.Stderr, "Extended updates would give away what the fog hides: %s\n", cmd)
+				return
+			}
+		}
+	}
+
 	game := sim.NewGame(constants)
 
 	if provided_frame == nil {
-		game.UseFrame(sim.MapGenOfficial(players, width, height, constants.INITIAL_ENERGY, seed))
+
+		var frame *sim.Frame
+
+		if constants.BOUNDED {
+			frame = sim.MapGenBounded(players, width, height, constants.INITIAL_ENERGY, seed)
+		} else {
+			frame = sim.MapGenOfficial(players, width, height, constants.INITIAL_ENERGY, seed)
+		}
+
+		if opts.Obstacles > 0 {
+			sim.AddObstacles(frame, opts.Obstacles, seed, constants.BOUNDED)
+		}
+
+		game.UseFrame(frame)
+
 	} else {
 		game.UseFrame(provided_frame)
 	}
 
 	initial_halite := game.TotalHalite()
 
-	json_blob_bytes, _ := json.Marshal(constants)
-	json_blob := string(json_blob_bytes)
-	json_blob = strings.Replace(json_blob, " ", "", -1)
+	transcript_list := make([]*Transcript, players)		// Entries stay nil if not recording
 
-	init_string := game.BotInitString()
+	if opts.Transcripts {
+		transcript_base := filepath.Join(opts.Folder, fmt.Sprintf("transcript-%v-%v-%v-%v",
+			time.Now().Format("20060102-150405-0700"), seed, width, height))
+		for pid := 0; pid < players; pid++ {
+			if bots[pid] {
+				transcript_list[pid] = NewTranscript(transcript_base, pid)
+			}
+		}
+	}
+
+	var remote_conns []net.Conn
+
+	for pid := 0; pid < players; pid++ {
+		if bots[pid] && parse_seat(opts.Botlist[pid]).Cmd == REMOTE_BOT {
+			remote_conns = append(remote_conns, nil)
+		}
+	}
+
+	if len(remote_conns) > 0 {
+		var err error
+		remote_conns, err = accept_remotes(opts.Listen, len(remote_conns))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+	}
 
 	var pregame string
 
 	for pid := 0; pid < players; pid++ {
-		pregame = fmt.Sprintf("%s\n%d %d\n%s", json_blob, players, pid, init_string)
-		go bot_handler(botlist[pid], pid, io_chans[pid], pregame)
+		pregame = game.PregameString(pid)
+		seat := parse_seat(opts.Botlist[pid])
+		cmd := seat.Cmd
+		var adapter *JSONAdapter
+		if seat.JSON {
+			adapter = &JSONAdapter{extended: seat.Extended}
+		}
+		if bots[pid] && cmd == HUMAN_BOT {
+			go human_handler(pid, io_chans[pid], pregame, transcript_list[pid])
+		} else if bots[pid] && cmd == REMOTE_BOT {
+			go remote_handler(remote_conns[0], pid, io_chans[pid], pregame, transcript_list[pid], adapter)
+			remote_conns = remote_conns[1:]
+		} else if bots[pid] {
+			go bot_handler(cmd, pid, io_chans[pid], pregame, transcript_list[pid], adapter)
+		}
 	}
 
-	if viewer {
+	if opts.Viewer {
 		print_with_newline(pregame)		// The viewer will get the POV of the final player
 	}
 
@@ -186,13 +665,29 @@ func main() {
 		player_names = append(player_names, "")
 	}
 
-	// Get names...
+	// Get names... (recorded players already have theirs)
 
 	names_received := 0
+
+	for pid, recording := range recordings {
+		if recording != nil && bots[pid] == false {
+			names_received++
+			if recording.Timeouts[0] {
+				player_names[pid] = "Non-starter (time)"
+				game.Kill(pid, 0)
+			} else {
+				player_names[pid] = recording.Lines[0]
+				if player_names[pid] == "" {
+					player_names[pid] = "(blank)"
+				}
+			}
+		}
+	}
+
 	deadline := time.NewTimer(30 * time.Second)
 
 	GetNames:
-	for {
+	for names_received < players {
 
 		select {
 
@@ -212,7 +707,7 @@ func main() {
 
 		case <- deadline.C:
 
-			if no_timeout {
+			if opts.NoTimeout {
 				continue GetNames
 			}
 
@@ -222,6 +717,7 @@ func main() {
 				if player_names[pid] == "" {
 					player_names[pid] = "Non-starter (time)"
 					game.Kill(pid, 0)
+					transcript_list[pid].RecordTimeout(0)
 				}
 			}
 
@@ -229,7 +725,7 @@ func main() {
 		}
 	}
 
-	if viewer {
+	if opts.Viewer {
 		j, _ := json.Marshal(player_names)
 		fmt.Fprintf(os.Stderr, "{\"viewer_info\":{\"names\":%v}}\n", string(j))
 	}
@@ -240,22 +736,48 @@ func main() {
 
 	// -----------------------------------------------------------------------------------------------------------------------
 
-	for turn := 0; turn <= turns; turn++ {				// Don't mess with this now, we expect <= below...
+	// A snapshot from mid-game already holds the state the bots should be sent first,
+	// i.e. the one our loop would have produced on the turn before it. So we start there,
+	// and send that state as it is, without simulating a turn of empty moves.
 
-		update_string, rf := game.UpdateFromMoves(move_strings)
-		replay.FullFrames = append(replay.FullFrames, rf)
+	resuming := provided_frame != nil && provided_frame.Turn() > 0
+	first_turn := 0
+
+	if resuming {
+		first_turn = provided_frame.Turn() - 1
+	}
+
+	for turn := first_turn; turn <= turns; turn++ {		// Don't mess with this now, we expect <= below...
+
+		var update_string string
+		var rf *sim.ReplayFrame
+
+		if turn == first_turn && resuming {
+			update_string = game.CurrentUpdateString()
+		} else {
+			update_string, rf = game.UpdateFromMoves(move_strings)
+			replay.FullFrames = append(replay.FullFrames, rf)
+		}
 
 		// Send on every turn except final...
 
 		if turn < turns {
+			extended_string := ""
 			for pid := 0; pid < players; pid++ {
-				if game.IsAlive(pid) {
-					io_chans[pid] <- update_string		// THIS WILL HANG THE ENGINE IF THE HANDLER ISN'T AT THE RIGHT PLACE. Care!
+				if game.IsAlive(pid) && bots[pid] {
+					to_send := game.PlayerUpdateString(pid, update_string)
+					if parse_seat(opts.Botlist[pid]).Extended {
+						if extended_string == "" {
+							extended_string = game.ExtendedUpdateString(update_string, rf)
+						}
+						to_send = extended_string
+					}
+					io_chans[pid] <- to_send			// THIS WILL HANG THE ENGINE IF THE HANDLER ISN'T AT THE RIGHT PLACE. Care!
 				}
 			}
 		}
 
-		if viewer {
+		if opts.Viewer {
 			print_with_newline(update_string)
 		}
 
@@ -274,6 +796,36 @@ func main() {
 			}
 		}
 
+		// Recorded players "send" immediately, and have their
+		// timeouts applied just as if the deadline was hit...
+		//
+		// If there's also a bot in the seat (i.e. we're before the branch
+		// turn) we still need its output, to keep the handler in step, but
+		// it will be discarded.
+
+		discard := make([]bool, players)
+
+		for pid := 0; pid < players; pid++ {
+
+			if received[pid] || recordings[pid] == nil || (bots[pid] && turn + 1 >= opts.BranchTurn) {
+				continue
+			}
+
+			move_strings[pid] = recordings[pid].Lines[turn + 1]
+
+			if recordings[pid].Timeouts[turn + 1] {		// Any late output from a live bot is ignored, as it's dead
+				move_strings[pid] = ""
+				game.Kill(pid, -1)
+			}
+
+			if bots[pid] && game.IsAlive(pid) {
+				discard[pid] = true
+			} else {
+				received[pid] = true
+				received_total++
+			}
+		}
+
 		wait_start_time := time.Now()
 
 		if received_total < players {
@@ -287,7 +839,22 @@ func main() {
 
 				case op := <- bot_output_chan:
 
-					if game.IsAlive(op.Pid) {		// Bot hasn't crashed (if it had, we already pretended it sent "")
+					if bots[op.Pid] == false {		// A hung bot we dropped (see below), replying at last
+						continue Wait
+					}
+
+					if discard[op.Pid] {			// Bot is still following its recording
+
+						discard[op.Pid] = false
+						received_total++
+						received[op.Pid] = true
+
+						if received_total >= players {
+							deadline.Stop()
+							break Wait
+						}
+
+					} else if game.IsAlive(op.Pid) {		// Bot hasn't crashed (if it had, we already pretended it sent "")
 
 						received_total++
 						received[op.Pid] = true
@@ -301,25 +868,42 @@ func main() {
 
 				case <- deadline.C:
 
-					if no_timeout {
+					if opts.NoTimeout {
 						continue Wait
 					}
 
+					// Bots following a recording aren't killed, since the recording already
+					// decided what happened this turn, and their output was to be discarded
+					// anyway. Instead we drop the bot, and the recording plays on alone.
+
 					for pid := 0; pid < players; pid++ {
-						if received[pid] == false {
+						if received[pid] == false && discard[pid] {
+							bots[pid] = false
+							discard[pid] = false
+							fmt.Fprintf(os.Stderr, "Hit the deadline. Dropping bot %v, its recording will play on\n", pid)
+							bot_log(pid, fmt.Sprintf("Timed out on turn %d, while following its recording; dropped.", turn + 1))
+							received[pid] = true
+							received_total++
+						} else if received[pid] == false {
 							move_strings[pid] = ""
 							game.Kill(pid, -1)
+							transcript_list[pid].RecordTimeout(turn + 1)		// The turn the bot was told it was
 							fmt.Fprintf(os.Stderr, "Hit the deadline. Killing bot %v\n", pid)
+							bot_log(pid, fmt.Sprintf("Timed out on turn %d.", turn + 1))
+							received[pid] = true
+							received_total++
 						}
 					}
 
-					break Wait
+					if received_total >= players {
+						break Wait
+					}
 				}
 			}
 		}
 
 		elapsed := time.Now().Sub(wait_start_time)
-		wanted := time.Duration(sleep) * time.Millisecond
+		wanted := time.Duration(opts.Sleep) * time.Millisecond
 
 		if elapsed < wanted {
 			time.Sleep(wanted - elapsed)
@@ -365,38 +949,73 @@ func main() {
 	}
 
 	replay_filename := ""
+	timestamp := time.Now().Format("20060102-150405-0700")
 
-	if no_replay == false {
+	if opts.NoReplay == false {
 
-		timestamp := time.Now().Format("20060102-150405-0700")
 
-		if infile != "" {
+		if opts.Playback != "" {
+			replay_filename = fmt.Sprintf("playback-%v-%v-%v-%v.hlt", timestamp, seed, width, height)
+		} else if opts.Infile != "" {
 			replay_filename = fmt.Sprintf("reload-%v-%v-%v-%v.hlt", timestamp, seed, width, height)
+		} else if opts.Snapshot != "" {
+			replay_filename = fmt.Sprintf("snapshot-%v-%v-%v-%v.hlt", timestamp, seed, width, height)
 		} else {
 			replay_filename = fmt.Sprintf("replay-%v-%v-%v-%v.hlt", timestamp, seed, width, height)
 		}
-		replay_filename = filepath.Join(folder, replay_filename)
-		replay.Dump(replay_filename)
+		replay_filename = filepath.Join(opts.Folder, replay_filename)
+		replay.Dump(replay_filename, opts.NoCompression == false)
 	}
 
-	if viewer == false {
+	if opts.Viewer == false && opts.OfficialResults {
 
-		type RankScore struct {
-			Cmd				string				`json:"cmd"`
-			Rank			int					`json:"rank"`
-			Score			int					`json:"score"`
+		official := new(OfficialStats)
+
+		official.ErrorLogs = make(map[int]string)
+		official.ExecutionTime = time.Now().Sub(start_time).Milliseconds()
+		official.FinalSnapshot = game.Snapshot()
+		official.MapGenerator = "Fractal Value Noise Tile"		// What Official calls the generator we copied
+		official.MapHeight = height
+		official.MapSeed = seed
+		official.MapTotalHalite = initial_halite
+		official.MapWidth = width
+		official.Replay = replay_filename
+		official.Stats = make(map[int]OfficialRankScore)
+		official.Terminated = make(map[int]bool)
+
+		if opts.Infile != "" || opts.InPNG != "" || opts.Playback != "" || opts.Snapshot != "" {
+			official.MapGenerator = "file"					// Official has no equivalent
 		}
 
-		type PrintedStats struct {
-			MapSeed			uint32				`json:"map_seed"`
-			MapWidth		int					`json:"map_width"`
-			MapHeight		int					`json:"map_height"`
-			MapHalite		int					`json:"map_halite"`
-			Replay			string				`json:"replay"`
-			Stats			map[int]RankScore	`json:"stats"`
-			Time			string				`json:"time"`
+		for pid := 0; pid < players; pid++ {
+
+			official.Stats[pid] = OfficialRankScore{
+				Rank: replay.Stats.Pstats[pid].Rank,
+				Score: replay.Stats.Pstats[pid].FinalProduction,
+				HasReplay: replay_filename != "",
+			}
+
+			official.Terminated[pid] = game.IsAlive(pid) == false
+
+			// Like Official, a player that was kicked gets an error log...
+
+			if game.IsAlive(pid) == false {
+				log_filename := filepath.Join(opts.Folder, fmt.Sprintf("errorlog-%v-%v-%v-%v-%v.log", timestamp, seed, width, height, pid))
+				err := write_error_log(log_filename, pid)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+				} else {
+					official.ErrorLogs[pid] = log_filename
+				}
+			}
 		}
 
+		foo, _ := json.MarshalIndent(official, "", "    ")
+
+		fmt.Println(string(foo))
+
+	} else if opts.Viewer == false {
+
 		ps := new(PrintedStats)
 
 		ps.MapSeed = seed
@@ -404,13 +1023,14 @@ func main() {
 		ps.MapHeight = height
 		ps.MapHalite = initial_halite
 		ps.Replay = replay_filename
+		ps.FinalSnapshot = game.Snapshot()
 		ps.Stats = make(map[int]RankScore)
 		ps.Time = time.Now().Sub(start_time).Round(time.Millisecond).String()
 
 		for pid := 0; pid < players; pid++ {
 
 			rankscore := RankScore{
-				Cmd: botlist[pid],
+				Cmd: opts.Botlist[pid],
 				Rank: replay.Stats.Pstats[pid].Rank,
 				Score: replay.Stats.Pstats[pid].FinalProduction,
 			}
@@ -420,8 +1040,7 @@ func main() {
 
 		foo, _ := json.MarshalIndent(ps, "", "    ")
 
-		fmt.Printf(string(foo))
-		fmt.Printf("\n")
+		fmt.Println(string(foo))
 	}
 
 	// Kill the bots fairly gracefully...
@@ -432,6 +1051,10 @@ func main() {
 
 	time.Sleep(250 * time.Millisecond)
 
+	for _, transcript := range transcript_list {
+		transcript.Close()
+	}
+
 	for _, cmd := range all_running_processes {
 		cmd.Process.Kill()
 	}
@@ -439,15 +1062,99 @@ func main() {
 
 // -----------------------------------------------------------------------------------------
 
-func parse_args() (
-		width, height, sleep int,
-		seed uint32,
-		no_timeout, no_replay, viewer bool,
-		folder, infile, inPNG string,
-		botlist []string) {
+type RankScore struct {
+	Cmd						string				`json:"cmd"`
+	Rank					int					`json:"rank"`
+	Score					int					`json:"score"`
+}
+
+type PrintedStats struct {							// What we print at the end of the game
+	MapSeed					uint32				`json:"map_seed"`
+	MapWidth				int					`json:"map_width"`
+	MapHeight				int					`json:"map_height"`
+	MapHalite				int					`json:"map_halite"`
+	Replay					string				`json:"replay"`
+	Stats					map[int]RankScore	`json:"stats"`
+	Time					string				`json:"time"`
+	FinalSnapshot			string				`json:"final_snapshot"`
+}
+
+type OfficialRankScore struct {
+	Rank					int					`json:"rank"`
+	Score					int					`json:"score"`
+	HasReplay				bool				`json:"has_replay"`
+}
+
+type OfficialStats struct {							// The same thing, in the Official engine's format
+	ErrorLogs				map[int]string				`json:"error_logs"`				// Only for players that were kicked
+	ExecutionTime			int64						`json:"execution_time"`			// Milliseconds
+	FinalSnapshot			string						`json:"final_snapshot"`
+	MapGenerator			string						`json:"map_generator"`
+	MapHeight				int							`json:"map_height"`
+	MapSeed					uint32						`json:"map_seed"`
+	MapTotalHalite			int							`json:"map_total_halite"`
+	MapWidth				int							`json:"map_width"`
+	Replay					string						`json:"replay"`
+	Stats					map[int]OfficialRankScore	`json:"stats"`
+	Terminated				map[int]bool				`json:"terminated"`				// i.e. the bot died
+}
+
+// -----------------------------------------------------------------------------------------
+
+type Options struct {
+	Width					int
+	Height					int
+	Sleep					int
+	Seed					uint32
+	NoTimeout				bool
+	NoReplay				bool
+	NoCompression			bool
+	Viewer					bool
+	OfficialResults			bool			// Print the results in the Official engine's format, not ours
+	Transcripts				bool
+	Folder					string
+	Infile					string
+	InPNG					string
+	Snapshot				string			// The snapshot string itself, or @filename
+	Playback				string			// Base name of a set of transcripts, to be played back without bots
+	Live					map[int]string	// pid --> bot to take over that seat in playback
+	BranchTurn				int				// First turn the live bots' moves are used
+	Tournament				string			// Results store; if set, we run a whole tournament of the bots
+	Seeds					[]uint32
+	Sizes					[]int
+	PlayerCounts			[]int
+	Ratings					string			// Results store to compute TrueSkill ratings from
+	SPRT					bool			// Play the 2 bots against each other until an SPRT decides
+	SPRTElo0				float64
+	SPRTElo1				float64
+	SPRTAlpha				float64
+	SPRTBeta				float64
+	SPRTMaxGames			int
+	Analyse					bool			// Treat the "bots" as results files / replays, and report on them
+	BotInputReplay			string			// If set, we just print a bot's input stream from this replay and quit
+	BotInputPid				int
+	Builtin					string			// If set, we are ourselves the named builtin bot
+	Listen					string			// Where remote bots connect, tcp:<host:port> or unix:<path>
+	Rules					string			// Name of the ruleset, see sim/rules.go
+	Bounded					bool			// The map's edges are walls
+	Obstacles				int				// Percentage of the generated map to make obstacles
+	Fog						int				// Fog of war radius, 0 for none
+	Regrowth				int				// Regrowth ratio, 0 for none
+	RegrowthTarget			int				// Percentage of initial halite that cells regrow towards
+	Botlist					[]string
+}
+
+func parse_args() *Options {
 
-	seed = uint32(time.Now().UTC().Unix())
-	folder = "./"
+	opts := new(Options)
+	opts.Live = make(map[int]string)
+	opts.RegrowthTarget = 50
+	opts.SPRTElo1 = 20
+	opts.SPRTAlpha = 0.05
+	opts.SPRTBeta = 0.05
+
+	opts.Seed = uint32(time.Now().UTC().Unix())
+	opts.Folder = "./"
 
 	dealt_with := make([]bool, len(os.Args))
 	dealt_with[0] = true
@@ -463,7 +1170,7 @@ func parse_args() (
 		if arg == "--width" || arg == "-w" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			width, err = strconv.Atoi(os.Args[n + 1])
+			opts.Width, err = strconv.Atoi(os.Args[n + 1])
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Couldn't understand stated width.\n")
 				os.Exit(1)
@@ -474,7 +1181,7 @@ func parse_args() (
 		if arg == "--height" || arg == "-h" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			height, err = strconv.Atoi(os.Args[n + 1])
+			opts.Height, err = strconv.Atoi(os.Args[n + 1])
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Couldn't understand stated height.\n")
 				os.Exit(1)
@@ -490,14 +1197,14 @@ func parse_args() (
 				fmt.Fprintf(os.Stderr, "Couldn't understand stated seed.\n")
 				os.Exit(1)
 			}
-			seed = uint32(seed64)
+			opts.Seed = uint32(seed64)
 			continue
 		}
 
 		if arg == "--sleep" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			sleep, err = strconv.Atoi(os.Args[n + 1])
+			opts.Sleep, err = strconv.Atoi(os.Args[n + 1])
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Couldn't understand stated sleep.\n")
 				os.Exit(1)
@@ -508,44 +1215,260 @@ func parse_args() (
 		if arg == "--file" || arg == "-f" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			infile = os.Args[n + 1]
+			opts.Infile = os.Args[n + 1]
 			continue
 		}
 
 		if arg == "--png" || arg == "-g" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			inPNG = os.Args[n + 1]
+			opts.InPNG = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--snapshot" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Snapshot = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--playback" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Playback = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--live" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			dealt_with[n + 2] = true
+			pid, err := strconv.Atoi(os.Args[n + 1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated pid.\n")
+				os.Exit(1)
+			}
+			opts.Live[pid] = os.Args[n + 2]
+			continue
+		}
+
+		if arg == "--branch" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.BranchTurn, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated branch turn.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--tournament" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Tournament = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--ratings" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Ratings = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--analyse" || arg == "--analyze" {
+			dealt_with[n] = true
+			opts.Analyse = true
+			continue
+		}
+
+		if arg == "--sprt" {
+			dealt_with[n] = true
+			opts.SPRT = true
+			continue
+		}
+
+		if arg == "--elo0" || arg == "--elo1" || arg == "--alpha" || arg == "--beta" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			val, err := strconv.ParseFloat(os.Args[n + 1], 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated %s.\n", arg[2:])
+				os.Exit(1)
+			}
+			switch arg {
+				case "--elo0": opts.SPRTElo0 = val
+				case "--elo1": opts.SPRTElo1 = val
+				case "--alpha": opts.SPRTAlpha = val
+				case "--beta": opts.SPRTBeta = val
+			}
+			continue
+		}
+
+		if arg == "--max-games" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.SPRTMaxGames, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated max games.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--seeds" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			for _, i := range parse_int_list(os.Args[n + 1], "seeds") {
+				opts.Seeds = append(opts.Seeds, uint32(i))
+			}
+			continue
+		}
+
+		if arg == "--sizes" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Sizes = parse_int_list(os.Args[n + 1], "sizes")
+			continue
+		}
+
+		if arg == "--player-counts" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.PlayerCounts = parse_int_list(os.Args[n + 1], "player counts")
 			continue
 		}
 
 		if arg == "--replay-directory" || arg == "-i" {
 			dealt_with[n] = true
 			dealt_with[n + 1] = true
-			folder = os.Args[n + 1]
+			opts.Folder = os.Args[n + 1]
 			continue
 		}
 
 		if arg == "--viewer" || arg == "-u" {
 			dealt_with[n] = true
-			viewer = true
+			opts.Viewer = true
+			continue
+		}
+
+		if arg == "--bot-input" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			dealt_with[n + 2] = true
+			opts.BotInputReplay = os.Args[n + 1]
+			opts.BotInputPid, err = strconv.Atoi(os.Args[n + 2])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated pid.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--listen" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Listen = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--rules" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Rules = os.Args[n + 1]
+			if _, ok := sim.RulesetByName(opts.Rules); ok == false {
+				fmt.Fprintf(os.Stderr, "Unknown rules \"%s\" (have: %s)\n", opts.Rules, strings.Join(sim.RulesetNames(), ", "))
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--fog" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Fog, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil || opts.Fog < 0 {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated fog radius.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--regrowth" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Regrowth, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil || opts.Regrowth < 0 {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated regrowth ratio.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--regrowth-target" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.RegrowthTarget, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil || opts.RegrowthTarget < 0 {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated regrowth target.\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--builtin" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Builtin = os.Args[n + 1]
+			continue
+		}
+
+		if arg == "--transcripts" {
+			dealt_with[n] = true
+			opts.Transcripts = true
+			continue
+		}
+
+		if arg == "--obstacles" {
+			dealt_with[n] = true
+			dealt_with[n + 1] = true
+			opts.Obstacles, err = strconv.Atoi(os.Args[n + 1])
+			if err != nil || opts.Obstacles < 0 || opts.Obstacles > 50 {
+				fmt.Fprintf(os.Stderr, "Couldn't understand stated obstacle percentage (0-50).\n")
+				os.Exit(1)
+			}
+			continue
+		}
+
+		if arg == "--bounded" {
+			dealt_with[n] = true
+			opts.Bounded = true
 			continue
 		}
 
 		if arg == "--no-timeout" {
 			dealt_with[n] = true
-			no_timeout = true
+			opts.NoTimeout = true
 			continue
 		}
 
 		if arg == "--no-replay" {
 			dealt_with[n] = true
-			no_replay = true
+			opts.NoReplay = true
+			continue
+		}
+
+		if arg == "--no-compression" {
+			dealt_with[n] = true
+			opts.NoCompression = true
 			continue
 		}
 
-		if arg == "--no-compression" {		// We already don't...
+		if arg == "--official-results" {
 			dealt_with[n] = true
+			opts.OfficialResults = true
 			continue
 		}
 
@@ -578,18 +1501,97 @@ func parse_args() (
 			continue
 		}
 
-		botlist = append(botlist, arg)
+		opts.Botlist = append(opts.Botlist, arg)
 	}
 
-	if width == 0 && height > 0 { width = height }
-	if height == 0 && width > 0 { height = width }
+	if opts.Width == 0 && opts.Height > 0 { opts.Width = opts.Height }
+	if opts.Height == 0 && opts.Width > 0 { opts.Height = opts.Width }
 
-	if width < 2 || width > 128 || height < 2 || height > 128 {
-		width = sim.SizeFromSeed(seed)
-		height = width
+	if opts.Width < 2 || opts.Width > 128 || opts.Height < 2 || opts.Height > 128 {
+		opts.Width = sim.SizeFromSeed(opts.Seed)
+		opts.Height = opts.Width
 	}
 
-	return width, height, sleep, seed, no_timeout, no_replay, viewer, folder, infile, inPNG, botlist
+	return opts
+}
+
+func parse_int_list(s string, what string) []int {
+
+	// e.g. "32,40" or "1-100" or "1-5,9"
+
+	var ret []int
+
+	for _, part := range strings.Split(s, ",") {
+
+		ends := strings.SplitN(part, "-", 2)
+
+		first, err1 := strconv.Atoi(ends[0])
+		last, err2 := first, error(nil)
+
+		if len(ends) == 2 {
+			last, err2 = strconv.Atoi(ends[1])
+		}
+
+		if err1 != nil || err2 != nil || last < first {
+			fmt.Fprintf(os.Stderr, "Couldn't understand stated %s.\n", what)
+			os.Exit(1)
+		}
+
+		for i := first; i <= last; i++ {
+			ret = append(ret, i)
+		}
+	}
+
+	return ret
+}
+
+// -----------------------------------------------------------------------------------------
+
+func print_bot_input(filename string, pid int) {
+
+	// Print the exact stream a bot would have been sent during the game in the
+	// replay, i.e. what it would have read from stdin had Dubnium run the game.
+
+	replay, err := sim.ReplayFromFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if pid < 0 || pid >= replay.NumPlayers {
+		fmt.Fprintf(os.Stderr, "Bad pid %d for this replay (players: %d)\n", pid, replay.NumPlayers)
+		os.Exit(1)
+	}
+
+	frames := replay.Frames()
+
+	constants := replay.Constants
+	constants.GameSeed = replay.Seed			// Not stored in replays but sent to bots
+
+	game := sim.NewGame(constants)
+	game.UseFrame(frames[0])
+
+	print_with_newline(game.PregameString(pid))
+
+	// The engine sends the state at the start of turns 1 to MAX_TURNS, but stops
+	// sending once the bot is dead. A bot is always sent exactly one update after
+	// its recorded death time (see the uses of Kill()).
+
+	last := constants.MAX_TURNS
+
+	if replay.Stats != nil {
+		for _, pstats := range replay.Stats.Pstats {
+			if pstats.Pid == pid && pstats.LastTurnAlive + 1 < last {
+				last = pstats.LastTurnAlive + 1
+			}
+		}
+	}
+
+	updates := sim.PlayerUpdateStrings(constants, frames, pid)		// Which are fogged, if the game was
+
+	for turn := 1; turn <= last && turn < len(frames); turn++ {
+		print_with_newline(updates[turn - 1])
+	}
 }
 
 // -----------------------------------------------------------------------------------------
@@ -605,7 +1607,7 @@ func turns_from_size(width, height int) int {
 }
 
 func print_with_newline(s string) {
-	fmt.Printf(s)
+	fmt.Print(s)
 	if len(s) == 0 || s[len(s) - 1] != '\n' {
 		fmt.Printf("\n")
 	}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"./sim"
+)
+
+var dubnium_binary string		// Built once by TestMain(), since builtin bots run a second copy of it
+
+func TestMain(m *testing.M) {
+
+	dir, err := ioutil.TempDir("", "dubnium-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	dubnium_binary = filepath.Join(dir, "dubnium")
+
+	out, err := exec.Command("go", "build", "-o", dubnium_binary, ".").CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s%v\n", out, err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func run_dubnium(t *testing.T, dir string, args ...string) string {
+
+	// Runs the engine in dir, returning its stdout.
+
+	cmd := exec.Command(dubnium_binary, args...)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("dubnium %s: %v", strings.Join(args, " "), err)
+	}
+
+	return string(out)
+}
+
+func TestSnapshotAtTurnOne(t *testing.T) {
+
+	// The bots' first update should be the snapshot's own state, not a turn later.
+
+	constants := sim.NewConstants(2, 32, 32, 400, 42)
+	game := sim.NewGame(constants)
+	game.UseFrame(sim.MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42))
+	game.UpdateFromMoves([]string{"g", ""})
+
+	out := run_dubnium(t, t.TempDir(), "--snapshot", game.Snapshot(), "--viewer", "--no-replay", "builtin:idle", "builtin:idle")
+
+	lines := strings.Split(out, "\n")
+	pregame_lines := 2 + 2 + 1 + 32					// JSON, players + pid, factories, size, map
+	expected := strings.Split(game.CurrentUpdateString(), "\n")
+
+	if len(lines) < pregame_lines + len(expected) {
+		t.Fatalf("Only got %d lines of output", len(lines))
+	}
+
+	got := strings.Join(lines[pregame_lines:pregame_lines + len(expected)], "\n")
+
+	if got != game.CurrentUpdateString() {
+		t.Errorf("First update was:\n%s\nexpected:\n%s", got, game.CurrentUpdateString())
+	}
+}
+
+// The shape of Official's --results-as-json output, for a 2 player game that one bot
+// crashed out of. Only the keys matter here, not the values.
+
+const OFFICIAL_RESULTS_SAMPLE = `{
+    "error_logs": {
+        "1": "replays/errorlog-20181023-114302+0100-1540291382-32-32-1.log"
+    },
+    "execution_time": 2764,
+    "final_snapshot": "0;32;32;1540291382;...",
+    "map_generator": "Fractal Value Noise Tile",
+    "map_height": 32,
+    "map_seed": 1540291382,
+    "map_total_halite": 178042,
+    "map_width": 32,
+    "replay": "replays/replay-20181023-114302+0100-1540291382-32-32.hlt",
+    "stats": {
+        "0": {
+            "has_replay": true,
+            "rank": 1,
+            "score": 4856
+        },
+        "1": {
+            "has_replay": true,
+            "rank": 2,
+            "score": 0
+        }
+    },
+    "terminated": {
+        "0": false,
+        "1": true
+    }
+}`
+
+func sorted_keys(m map[string]interface{}) []string {
+	var ret []string
+	for key := range m {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestOfficialResults(t *testing.T) {
+
+	dir := t.TempDir()
+	out := run_dubnium(t, dir, "--official-results", "--width", "32", "--height", "32", "--seed", "42", "builtin:idle", "builtin:idle")
+
+	var sample, got map[string]interface{}
+
+	if err := json.Unmarshal([]byte(OFFICIAL_RESULTS_SAMPLE), &sample); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if fmt.Sprint(sorted_keys(got)) != fmt.Sprint(sorted_keys(sample)) {
+		t.Errorf("Keys were %v, expected %v", sorted_keys(got), sorted_keys(sample))
+	}
+
+	for _, pid := range []string{"0", "1"} {
+		got_stats, _ := got["stats"].(map[string]interface{})[pid].(map[string]interface{})
+		sample_stats := sample["stats"].(map[string]interface{})[pid].(map[string]interface{})
+		if fmt.Sprint(sorted_keys(got_stats)) != fmt.Sprint(sorted_keys(sample_stats)) {
+			t.Errorf("Player %s's stats keys were %v, expected %v", pid, sorted_keys(got_stats), sorted_keys(sample_stats))
+		}
+		if _, ok := got["terminated"].(map[string]interface{})[pid].(bool); ok == false {
+			t.Errorf("No terminated entry for player %s", pid)
+		}
+	}
+
+	// And --analyse should be able to read it, even without bot names...
+
+	filename := filepath.Join(dir, "official.json")
+	ioutil.WriteFile(filename, []byte(out), 0644)
+
+	results, err := results_from_file(filename)
+	if err != nil || len(results) != 1 || len(results[0].Players) != 2 {
+		t.Fatalf("results_from_file: got %v, %v", results, err)
+	}
+
+	for pid, player := range results[0].Players {
+		if player.Cmd != fmt.Sprintf("seat %d", pid) || player.Seat != pid {
+			t.Errorf("Player %d was read as %q in seat %d", pid, player.Cmd, player.Seat)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package hlt
+
+// A starter kit for Go bots, using the engine's own types. Typical use:
+//
+//		game, err := hlt.NewGame(os.Stdin, os.Stdout)
+//		game.Ready("MyBot")
+//		for game.Update() == nil {
+//			var moves []sim.Move
+//			for _, ship := range game.Me().Ships {
+//				moves = append(moves, hlt.MoveShip(ship.Sid, "n"))
+//			}
+//			game.Send(moves)
+//		}
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"../sim"
+)
+
+type Player struct {
+	Pid							int
+	Budget						int
+	Factory						sim.Dropoff
+	Ships						[]sim.Ship
+	Dropoffs					[]sim.Dropoff		// Not including the factory
+}
+
+type Game struct {
+	Constants					*sim.Constants
+	Pid							int
+	Width						int
+	Height						int
+	Turn						int					// 0 until the first update
+	Halite						[][]int				// Indexed [x][y], like the engine
+	Players						[]*Player			// Indexed by pid
+
+	ExtendedUpdates				bool				// Set this if the engine was told full:<command>
+	Extended					*sim.ExtendedInfo	// Then this is read each update, and ships' Inspired is set
+
+	in							*bufio.Reader
+	out							io.Writer
+}
+
+func NewGame(in io.Reader, out io.Writer) (*Game, error) {
+
+	// Reads the pregame. Doesn't send anything; call Ready() when set up.
+
+	self := new(Game)
+	self.in = bufio.NewReader(in)
+	self.out = out
+
+	line, err := self.in.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	self.Constants = new(sim.Constants)
+
+	err = json.Unmarshal([]byte(line), self.Constants)
+	if err != nil {
+		return nil, fmt.Errorf("hlt.NewGame: constants: %v", err)
+	}
+
+	vals, err := self.read_ints(2)
+	if err != nil {
+		return nil, err
+	}
+
+	players := vals[0]
+	self.Pid = vals[1]
+
+	for n := 0; n < players; n++ {
+
+		vals, err = self.read_ints(3)
+		if err != nil {
+			return nil, err
+		}
+
+		self.Players = append(self.Players, &Player{
+			Pid: vals[0],
+			Factory: sim.Dropoff{Factory: true, Owner: vals[0], Sid: -1, X: vals[1], Y: vals[2]},
+		})
+	}
+
+	vals, err = self.read_ints(2)
+	if err != nil {
+		return nil, err
+	}
+
+	self.Width, self.Height = vals[0], vals[1]
+
+	self.Halite = make([][]int, self.Width)
+	for x := 0; x < self.Width; x++ {
+		self.Halite[x] = make([]int, self.Height)
+	}
+
+	for y := 0; y < self.Height; y++ {
+
+		vals, err = self.read_ints(self.Width)
+		if err != nil {
+			return nil, err
+		}
+
+		for x := 0; x < self.Width; x++ {
+			self.Halite[x][y] = vals[x]
+		}
+	}
+
+	return self, nil
+}
+
+func (self *Game) Ready(name string) error {
+	_, err := fmt.Fprintf(self.out, "%s\n", name)
+	return err
+}
+
+func (self *Game) Update() error {
+
+	// Reads the next update. Returns io.EOF when the engine has finished with us.
+
+	vals, err := self.read_ints(1)
+	if err != nil {
+		return err
+	}
+
+	self.Turn = vals[0]
+
+	for n := 0; n < len(self.Players); n++ {
+
+		vals, err = self.read_ints(4)
+		if err != nil {
+			return err
+		}
+
+		pid, ship_count, dropoff_count := vals[0], vals[1], vals[2]
+
+		if pid < 0 || pid >= len(self.Players) {
+			return fmt.Errorf("hlt.Update: bad pid %d", pid)
+		}
+
+		player := self.Players[pid]
+		player.Budget = vals[3]
+		player.Ships = nil
+		player.Dropoffs = nil
+
+		for i := 0; i < ship_count; i++ {
+
+			vals, err = self.read_ints(4)
+			if err != nil {
+				return err
+			}
+
+			player.Ships = append(player.Ships, sim.Ship{Owner: pid, Sid: vals[0], X: vals[1], Y: vals[2], Halite: vals[3]})
+		}
+
+		for i := 0; i < dropoff_count; i++ {
+
+			vals, err = self.read_ints(3)
+			if err != nil {
+				return err
+			}
+
+			player.Dropoffs = append(player.Dropoffs, sim.Dropoff{Owner: pid, Sid: vals[0], X: vals[1], Y: vals[2]})
+		}
+	}
+
+	vals, err = self.read_ints(1)
+	if err != nil {
+		return err
+	}
+
+	cell_count := vals[0]
+
+	for i := 0; i < cell_count; i++ {
+
+		vals, err = self.read_ints(3)
+		if err != nil {
+			return err
+		}
+
+		self.Halite[self.wrap_x(vals[0])][self.wrap_y(vals[1])] = vals[2]
+	}
+
+	if self.ExtendedUpdates {
+		self.Extended, err = sim.ReadExtendedInfo(self.read_line, len(self.Players))
+		if err != nil {
+			return err
+		}
+		self.set_inspiration(self.Extended.Inspired)
+	}
+
+	return nil
+}
+
+func (self *Game) Send(moves []sim.Move) error {
+	_, err := fmt.Fprintf(self.out, "%s\n", sim.MovesString(moves))
+	return err
+}
+
+func (self *Game) read_line() (string, error) {
+	line, err := self.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return line, nil
+}
+
+func (self *Game) read_ints(n int) ([]int, error) {
+
+	// Reads 1 line, which should hold exactly n ints.
+
+	line, err := self.read_line()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(line)
+
+	if len(fields) != n {
+		return nil, fmt.Errorf("hlt: expected %d values, got line %q", n, line)
+	}
+
+	var ret []int
+
+	for _, field := range fields {
+		val, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("hlt: %v", err)
+		}
+		ret = append(ret, val)
+	}
+
+	return ret, nil
+}
+
+// ------------------------------------------------------------------------------------------
+
+func (self *Game) Me() *Player {
+	return self.Players[self.Pid]
+}
+
+func (self *Game) ShipAt(x, y int) (sim.Ship, bool) {
+	if self.OnMap(x, y) == false {
+		return sim.Ship{}, false
+	}
+	x, y = self.wrap_x(x), self.wrap_y(y)
+	for _, player := range self.Players {
+		for _, ship := range player.Ships {
+			if ship.X == x && ship.Y == y {
+				return ship, true
+			}
+		}
+	}
+	return sim.Ship{}, false
+}
+
+func (self *Game) StructureAt(x, y int) (sim.Dropoff, bool) {
+
+	// Factories and dropoffs.
+
+	if self.OnMap(x, y) == false {
+		return sim.Dropoff{}, false
+	}
+	x, y = self.wrap_x(x), self.wrap_y(y)
+	for _, player := range self.Players {
+		if player.Factory.X == x && player.Factory.Y == y {
+			return player.Factory, true
+		}
+		for _, dropoff := range player.Dropoffs {
+			if dropoff.X == x && dropoff.Y == y {
+				return dropoff, true
+			}
+		}
+	}
+	return sim.Dropoff{}, false
+}
+
+func (self *Game) HaliteAt(x, y int) int {
+	if self.OnMap(x, y) == false {
+		return 0						// Off the edge of a bounded map
+	}
+	return self.Halite[self.wrap_x(x)][self.wrap_y(y)]
+}
+
+func (self *Game) set_inspiration(sids []int) {
+
+	inspired := make(map[int]bool)
+	for _, sid := range sids {
+		inspired[sid] = true
+	}
+
+	for _, player := range self.Players {
+		for i := range player.Ships {
+			player.Ships[i].Inspired = inspired[player.Ships[i].Sid]
+		}
+	}
+}
+
+func (self *Game) MoveCost(ship sim.Ship) int {
+
+	// What the ship would pay to leave its cell. If it has less than this, it can't move.
+	// Ships are only ever marked Inspired with ExtendedUpdates set, since the normal
+	// update doesn't say; without them, this is the uninspired cost, which may be too high.
+
+	mcr := self.Constants.MOVE_COST_RATIO
+	if ship.Inspired { mcr = self.Constants.INSPIRED_MOVE_COST_RATIO }
+
+	return self.HaliteAt(ship.X, ship.Y) / mcr
+}
+
+func (self *Game) IsFinalTurn() bool {
+	return self.Turn >= self.Constants.MAX_TURNS
+}
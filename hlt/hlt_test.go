@@ -0,0 +1,290 @@
+package hlt
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"../sim"
+)
+
+// Play a game in the engine, feed what it sends to a bot into the package,
+// and check the package's idea of the state matches the engine's at every turn.
+
+func TestAgainstEngine(t *testing.T) {
+
+	players, width, height := 4, 40, 40
+
+	constants := sim.NewConstants(players, width, height, 300, 7)
+	engine := sim.NewGame(constants)
+	engine.UseFrame(sim.MapGenOfficial(players, width, height, constants.INITIAL_ENERGY, 7))
+
+	pid := 2
+
+	stream := engine.PregameString(pid) + "\n"
+	var frames []*sim.Frame
+
+	rng := rand.New(rand.NewSource(1))
+
+	for turn := 0; turn < 100; turn++ {
+
+		moves := make([][]sim.Move, players)
+
+		for p := 0; p < players; p++ {
+			for _, ship := range engine.ShipsOf(p) {
+				moves[p] = append(moves[p], MoveShip(ship.Sid, "nsewo"[rng.Intn(5):][:1]))
+			}
+			if engine.Budget(p) >= 1000 && rng.Intn(4) == 0 {
+				moves[p] = append(moves[p], Generate())
+			}
+		}
+
+		update, _, _ := engine.UpdateFromTypedMoves(moves)
+		stream += update + "\n"
+		frames = append(frames, engine.Frame())
+	}
+
+	game, err := NewGame(strings.NewReader(stream), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if game.Pid != pid || len(game.Players) != players || game.Width != width || game.Height != height {
+		t.Fatalf("Pregame: got pid %d, %d players, %dx%d", game.Pid, len(game.Players), game.Width, game.Height)
+	}
+
+	if reflect.DeepEqual(*game.Constants, *constants) == false {
+		t.Errorf("Constants differ")
+	}
+
+	for _, frame := range frames {
+
+		err := game.Update()
+		if err != nil {
+			t.Fatalf("Turn %d: %v", frame.Turn(), err)
+		}
+
+		if game.Turn != frame.Turn() {
+			t.Fatalf("Got turn %d, expected %d", game.Turn, frame.Turn())
+		}
+
+		if reflect.DeepEqual(game.Halite, frame.Halite()) == false {
+			t.Fatalf("Turn %d: halite differs", game.Turn)
+		}
+
+		for p, player := range game.Players {
+
+			if player.Budget != frame.Budget(p) {
+				t.Errorf("Turn %d: player %d budget %d, expected %d", game.Turn, p, player.Budget, frame.Budget(p))
+			}
+
+			if player.Factory.X != frame.Factory(p).X || player.Factory.Y != frame.Factory(p).Y {
+				t.Errorf("Player %d factory in wrong place", p)
+			}
+
+			expected := frame.ShipsOf(p)
+
+			if len(player.Ships) != len(expected) {
+				t.Fatalf("Turn %d: player %d has %d ships, expected %d", game.Turn, p, len(player.Ships), len(expected))
+			}
+
+			for i, ship := range player.Ships {
+				ship.Inspired = expected[i].Inspired		// Only sent in extended updates
+				if ship != expected[i] {
+					t.Fatalf("Turn %d: got ship %+v, expected %+v", game.Turn, ship, expected[i])
+				}
+			}
+		}
+	}
+
+	if game.Update() != io.EOF {
+		t.Errorf("Expected io.EOF at the end")
+	}
+}
+
+func TestInspiration(t *testing.T) {
+
+	// With extended updates, ships are marked inspired just as in the engine, and
+	// MoveCost() uses the inspired ratio for them.
+
+	players, width, height := 4, 32, 32
+
+	constants := sim.NewConstants(players, width, height, 300, 7)
+	engine := sim.NewGame(constants)
+	engine.UseFrame(sim.MapGenOfficial(players, width, height, constants.INITIAL_ENERGY, 7))
+
+	stream := engine.PregameString(0) + "\n"
+	var frames []*sim.Frame
+
+	nav := &Game{Width: width, Height: height}
+
+	for turn := 0; turn < 100; turn++ {
+
+		moves := make([][]sim.Move, players)
+
+		for p := 0; p < players; p++ {
+			for _, ship := range engine.ShipsOf(p) {
+				dirs := append(nav.DirectionsTowards(ship.X, ship.Y, width / 2, height / 2), "o")	// Crowd the middle
+				moves[p] = append(moves[p], MoveShip(ship.Sid, dirs[turn % len(dirs)]))
+			}
+			if engine.Budget(p) >= 1000 {
+				moves[p] = append(moves[p], Generate())
+			}
+		}
+
+		update, rf, _ := engine.UpdateFromTypedMoves(moves)
+		stream += engine.ExtendedUpdateString(update, rf) + "\n"
+		frames = append(frames, engine.Frame())
+	}
+
+	game, err := NewGame(strings.NewReader(stream), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	game.ExtendedUpdates = true
+	inspired_count := 0
+
+	for _, frame := range frames {
+
+		if err := game.Update(); err != nil {
+			t.Fatalf("Turn %d: %v", frame.Turn(), err)
+		}
+
+		for p, player := range game.Players {
+			for i, ship := range player.Ships {
+
+				expected := frame.ShipsOf(p)[i]
+
+				if ship != expected {
+					t.Fatalf("Turn %d: got ship %+v, expected %+v", game.Turn, ship, expected)
+				}
+
+				mcr := constants.MOVE_COST_RATIO
+				if ship.Inspired {
+					mcr = constants.INSPIRED_MOVE_COST_RATIO
+					inspired_count++
+				}
+
+				if cost := game.MoveCost(ship); cost != frame.HaliteAt(ship.X, ship.Y) / mcr {
+					t.Fatalf("Turn %d: MoveCost of %+v was %d", game.Turn, ship, cost)
+				}
+			}
+		}
+	}
+
+	if inspired_count == 0 {
+		t.Errorf("No ship was ever inspired, so nothing was tested")
+	}
+}
+
+func TestDistance(t *testing.T) {
+
+	game := &Game{Width: 32, Height: 32}
+
+	tests := []struct {
+		x1, y1, x2, y2, d		int
+	}{
+		{0, 0, 0, 0, 0},
+		{0, 0, 3, 4, 7},
+		{0, 0, 31, 31, 2},
+		{1, 30, 30, 1, 6},
+		{0, 0, 16, 16, 32},
+	}
+
+	for _, test := range tests {
+		if d := game.Distance(test.x1, test.y1, test.x2, test.y2); d != test.d {
+			t.Errorf("Distance(%d, %d, %d, %d): got %d, expected %d", test.x1, test.y1, test.x2, test.y2, d, test.d)
+		}
+	}
+
+	if dirs := game.DirectionsTowards(0, 0, 31, 2); reflect.DeepEqual(dirs, []string{"w", "s"}) == false {
+		t.Errorf("DirectionsTowards: got %v", dirs)
+	}
+
+	// And with walls at the edges...
+
+	game.Constants = &sim.Constants{BOUNDED: true}
+
+	if d := game.Distance(0, 0, 31, 31); d != 62 {
+		t.Errorf("Bounded Distance: got %d, expected 62", d)
+	}
+
+	if dirs := game.DirectionsTowards(0, 0, 31, 2); reflect.DeepEqual(dirs, []string{"e", "s"}) == false {
+		t.Errorf("Bounded DirectionsTowards: got %v", dirs)
+	}
+
+	if x, y := game.Step(0, 5, "w"); x != 0 || y != 5 {
+		t.Errorf("Bounded Step: got %d %d", x, y)
+	}
+}
+
+func TestDirectionsAround(t *testing.T) {
+
+	// A wall at x = 5 from y = 0 to 10, on a 16x16 torus. Going from 4,4 to 6,4 the short
+	// way means going round the nearer end of the wall, i.e. north (wrapping round) first.
+
+	game := &Game{Width: 16, Height: 16}
+
+	game.Halite = make([][]int, 16)
+	for x := range game.Halite {
+		game.Halite[x] = make([]int, 16)
+	}
+	for y := 0; y <= 10; y++ {
+		game.Halite[5][y] = sim.OBSTACLE
+	}
+
+	if dirs := game.DirectionsAround(4, 4, 6, 4); reflect.DeepEqual(dirs, []string{"n"}) == false {
+		t.Errorf("DirectionsAround: got %v", dirs)
+	}
+
+	if d := game.WalkingDistances(6, 4)[4][4]; d != 12 {
+		t.Errorf("WalkingDistances: got %d, expected 12", d)
+	}
+
+	// Without obstacles it's the same as DirectionsTowards()...
+
+	game.Halite[5] = make([]int, 16)
+
+	if dirs := game.DirectionsAround(4, 4, 6, 4); reflect.DeepEqual(dirs, []string{"e"}) == false {
+		t.Errorf("DirectionsAround without obstacles: got %v", dirs)
+	}
+}
+
+func TestImportFromOutside(t *testing.T) {
+
+	// A bot kept elsewhere should be able to use the kit, by a relative import path
+	// (which also finds ../sim relative to the kit itself).
+
+	kit, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	rel, err := filepath.Rel(dir, kit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot := "package main\n\nimport \"" + filepath.ToSlash(rel) + "\"\n\nfunc main() {\n\thlt.NewGame(nil, nil)\n}\n"
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bot.go"), []byte(bot), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "build", "-o", filepath.Join(dir, "bot"), "bot.go")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Building a bot in %s: %v\n%s", dir, err, out)
+	}
+}
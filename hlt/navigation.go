@@ -0,0 +1,234 @@
+package hlt
+
+import (
+	"../sim"
+)
+
+var Directions = []string{"n", "s", "e", "w"}		// Not including "o", staying still
+
+func Offset(direction string) (int, int) {
+
+	switch direction {
+	case "n":
+		return 0, -1
+	case "s":
+		return 0, 1
+	case "e":
+		return 1, 0
+	case "w":
+		return -1, 0
+	}
+
+	return 0, 0
+}
+
+func Opposite(direction string) string {
+
+	switch direction {
+	case "n":
+		return "s"
+	case "s":
+		return "n"
+	case "e":
+		return "w"
+	case "w":
+		return "e"
+	}
+
+	return "o"
+}
+
+// ------------------------------------------------------------------------------------------
+// The map is a torus, so everything wraps; unless Constants.BOUNDED is set, in which
+// case the edges are walls and a ship moving into one stays where it is. The same goes
+// for obstacles, which are cells with -1 halite. DirectionsTowards() ignores them, but
+// DirectionsAround() walks round them.
+
+func mod(x, n int) int {
+	return (x % n + n) % n
+}
+
+func (self *Game) wrap_x(x int) int {
+	return mod(x, self.Width)
+}
+
+func (self *Game) wrap_y(y int) int {
+	return mod(y, self.Height)
+}
+
+func (self *Game) Bounded() bool {
+	return self.Constants != nil && self.Constants.BOUNDED
+}
+
+func (self *Game) OnMap(x, y int) bool {
+
+	// Always true for a torus, where any coordinates are somewhere.
+
+	return self.Bounded() == false || (x >= 0 && x < self.Width && y >= 0 && y < self.Height)
+}
+
+func (self *Game) IsObstacle(x, y int) bool {
+	return self.OnMap(x, y) && self.HaliteAt(x, y) == sim.OBSTACLE
+}
+
+func (self *Game) Normalize(x, y int) (int, int) {
+	return self.wrap_x(x), self.wrap_y(y)
+}
+
+func (self *Game) Step(x, y int, direction string) (int, int) {
+
+	// Where a ship at x, y would be after moving in the direction.
+
+	dx, dy := Offset(direction)
+
+	if self.OnMap(x + dx, y + dy) == false || self.IsObstacle(x + dx, y + dy) {
+		return x, y
+	}
+
+	return self.Normalize(x + dx, y + dy)
+}
+
+func (self *Game) dx_dy(x1, y1, x2, y2 int) (int, int) {
+
+	// The shortest signed offsets from 1 to 2, going either way round.
+
+	if self.Bounded() {
+		return x2 - x1, y2 - y1
+	}
+
+	dx := self.wrap_x(x2 - x1)
+	dy := self.wrap_y(y2 - y1)
+
+	if dx > self.Width / 2 {
+		dx -= self.Width
+	}
+	if dy > self.Height / 2 {
+		dy -= self.Height
+	}
+
+	return dx, dy
+}
+
+func (self *Game) Distance(x1, y1, x2, y2 int) int {
+
+	// Manhattan distance, the short way round.
+
+	dx, dy := self.dx_dy(x1, y1, x2, y2)
+
+	if dx < 0 { dx = -dx }
+	if dy < 0 { dy = -dy }
+
+	return dx + dy
+}
+
+func (self *Game) DirectionsTowards(x1, y1, x2, y2 int) []string {
+
+	// The directions that bring 1 closer to 2 (none if already there). No
+	// thought is given to collisions.
+
+	var ret []string
+
+	dx, dy := self.dx_dy(x1, y1, x2, y2)
+
+	if dx > 0 {
+		ret = append(ret, "e")
+	} else if dx < 0 {
+		ret = append(ret, "w")
+	}
+
+	if dy > 0 {
+		ret = append(ret, "s")
+	} else if dy < 0 {
+		ret = append(ret, "n")
+	}
+
+	return ret
+}
+
+func (self *Game) HasObstacles() bool {
+	for x := 0; x < self.Width; x++ {
+		for y := 0; y < self.Height; y++ {
+			if self.Halite[x][y] == sim.OBSTACLE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *Game) WalkingDistances(x, y int) [][]int {
+
+	// How many moves it takes to get from each cell to x, y, going round obstacles.
+	// Indexed [x][y]. -1 means it can't be done.
+
+	ret := make([][]int, self.Width)
+	for i := range ret {
+		ret[i] = make([]int, self.Height)
+		for j := range ret[i] {
+			ret[i][j] = -1
+		}
+	}
+
+	x, y = self.Normalize(x, y)
+	ret[x][y] = 0
+
+	queue := []sim.Position{{X: x, Y: y}}
+
+	for len(queue) > 0 {
+
+		pos := queue[0]
+		queue = queue[1:]
+
+		for _, direction := range Directions {			// Moves are reversible, so this works either way
+			nx, ny := self.Step(pos.X, pos.Y, direction)
+			if ret[nx][ny] == -1 {
+				ret[nx][ny] = ret[pos.X][pos.Y] + 1
+				queue = append(queue, sim.Position{X: nx, Y: ny})
+			}
+		}
+	}
+
+	return ret
+}
+
+func (self *Game) DirectionsAround(x1, y1, x2, y2 int) []string {
+
+	// The directions that start a shortest walk from 1 to 2, round any obstacles
+	// (none if already there, or if it can't be done). Without obstacles, this is
+	// just DirectionsTowards().
+
+	if self.HasObstacles() == false {
+		return self.DirectionsTowards(x1, y1, x2, y2)
+	}
+
+	distances := self.WalkingDistances(x2, y2)
+
+	x1, y1 = self.Normalize(x1, y1)
+	here := distances[x1][y1]
+
+	var ret []string
+
+	for _, direction := range Directions {
+		nx, ny := self.Step(x1, y1, direction)
+		if distances[nx][ny] != -1 && distances[nx][ny] < here {
+			ret = append(ret, direction)
+		}
+	}
+
+	return ret
+}
+
+// ------------------------------------------------------------------------------------------
+// Command builders. Game.Send() takes a list of these.
+
+func Generate() sim.Move {
+	return sim.Move{Type: "g"}
+}
+
+func MoveShip(sid int, direction string) sim.Move {
+	return sim.Move{Type: "m", Sid: sid, Direction: direction}
+}
+
+func Construct(sid int) sim.Move {
+	return sim.Move{Type: "c", Sid: sid}
+}
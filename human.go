@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"./sim"
+)
+
+// A person playing one seat from the terminal. It obeys the same contract as
+// bot_handler(), i.e. it sends its name and then one line of moves per update
+// via bot_output_chan, but reads the moves from our own stdin. Everything it
+// shows goes to stderr, since stdout is for the results.
+//
+// Moves are checked by simulating them with the engine's own rules, on the
+// state as the bot protocol describes it, so a mistake can just be retyped.
+
+const HUMAN_BOT = "human"
+
+const HUMAN_VIEW_RADIUS = 3
+
+func human_handler(pid int, io_chan chan string, pregame string, transcript *Transcript) {
+
+	frame, constants, err := sim.FrameFromPregameString(pregame)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Human: %v\n", err)
+		bot_output_chan <- BotOutput{pid, "Non-starter (pregame)"}
+		return
+	}
+
+	// Record the input as if it had been sent to a real bot...
+
+	w := transcript.Wrap(io.Discard)
+	fmt.Fprintf(w, "%s\n", pregame)
+
+	stdin := bufio.NewScanner(os.Stdin)
+
+	fmt.Fprintf(os.Stderr, "\nYou are player %d, on a %dx%d map. Your factory is at %d %d.\n",
+		pid, frame.Width(), frame.Height(), frame.Factory(pid).X, frame.Factory(pid).Y)
+	fmt.Fprintf(os.Stderr, "For each ship, type n, s, e, w, o (stay) or c (construct). Blank means o.\n")
+	fmt.Fprintf(os.Stderr, "Maps show halite / 10, and: @ this ship, S your ships, x enemy ships, F your structures, E theirs.\n")
+
+	transcript.Record(0, "Human")
+	bot_output_chan <- BotOutput{pid, "Human"}
+
+	for {
+
+		update := <- io_chan
+
+		fmt.Fprintf(w, "%s\n", update)
+
+		frame, err = sim.FrameFromUpdate(frame, update)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Human: %v\n", err)
+			bot_output_chan <- BotOutput{pid, ""}
+			continue
+		}
+
+		var line string
+
+		for {
+
+			moves := human_moves(frame, constants, pid, stdin)
+
+			// Check them on a throwaway game...
+
+			game := sim.NewGame(constants)
+			game.UseFrame(frame.Copy())
+
+			all_moves := make([][]sim.Move, frame.Players())
+			all_moves[pid] = moves
+
+			_, _, errors := game.Simulate(all_moves)
+
+			if len(errors) == 0 {
+				line = sim.MovesString(moves)
+				break
+			}
+
+			for _, err := range errors {
+				fmt.Fprintf(os.Stderr, "Not allowed: %s. Try again.\n", err.Message)
+			}
+		}
+
+		transcript.Record(frame.Turn(), line)
+		bot_output_chan <- BotOutput{pid, line}
+	}
+}
+
+func human_moves(frame *sim.Frame, constants *sim.Constants, pid int, stdin *bufio.Scanner) []sim.Move {
+
+	var moves []sim.Move
+
+	fmt.Fprintf(os.Stderr, "\n======== Turn %d of %d ======== Budget: %d ========\n", frame.Turn(), constants.MAX_TURNS, frame.Budget(pid))
+
+	for _, ship := range frame.ShipsOf(pid) {
+
+		fmt.Fprintf(os.Stderr, "\n%s\n", human_view(frame, pid, ship, constants.BOUNDED))
+		fmt.Fprintf(os.Stderr, "Ship %d at %d %d carrying %d, cell %d, move cost %d > ",
+			ship.Sid, ship.X, ship.Y, ship.Halite, frame.HaliteAt(ship.X, ship.Y), frame.HaliteAt(ship.X, ship.Y) / constants.MOVE_COST_RATIO)
+
+		answer := human_read(stdin, "nsewco")
+
+		if answer == "c" {
+			moves = append(moves, sim.Move{Type: "c", Sid: ship.Sid})
+		} else if answer != "o" {
+			moves = append(moves, sim.Move{Type: "m", Sid: ship.Sid, Direction: answer})
+		}
+	}
+
+	if frame.Budget(pid) >= constants.NEW_ENTITY_ENERGY_COST {
+
+		fmt.Fprintf(os.Stderr, "\nGenerate a ship for %d? (y/n) > ", constants.NEW_ENTITY_ENERGY_COST)
+
+		if human_read(stdin, "yn") == "y" {
+			moves = append(moves, sim.Move{Type: "g"})
+		}
+	}
+
+	return moves
+}
+
+func human_read(stdin *bufio.Scanner, allowed string) string {
+
+	// Reads 1 letter out of those allowed, asking again till it gets one. Blank
+	// (or EOF) is the last letter allowed, i.e. the harmless option.
+
+	for {
+
+		if stdin.Scan() == false {
+			return allowed[len(allowed) - 1:]
+		}
+
+		answer := strings.ToLower(strings.TrimSpace(stdin.Text()))
+
+		if answer == "" {
+			return allowed[len(allowed) - 1:]
+		}
+
+		if len(answer) == 1 && strings.Contains(allowed, answer) {
+			return answer
+		}
+
+		fmt.Fprintf(os.Stderr, "Please type one of: %s > ", strings.Join(strings.Split(allowed, ""), " "))
+	}
+}
+
+func human_view(frame *sim.Frame, pid int, ship sim.Ship, bounded bool) string {
+
+	// The cells around the ship, each as a marker and halite / 10:
+	//
+	//		@ this ship, S our ship, x enemy ship, F our structure, E enemy structure
+	//
+	// Obstacles, and cells off the edge of a bounded map, are shown as ####.
+
+	var lines []string
+
+	for dy := -HUMAN_VIEW_RADIUS; dy <= HUMAN_VIEW_RADIUS; dy++ {
+
+		var cells []string
+
+		for dx := -HUMAN_VIEW_RADIUS; dx <= HUMAN_VIEW_RADIUS; dx++ {
+
+			x, y := ship.X + dx, ship.Y + dy
+			marker := " "
+
+			if frame.IsObstacle(x, y) || (bounded && (x < 0 || x >= frame.Width() || y < 0 || y >= frame.Height())) {
+				cells = append(cells, "####")
+				continue
+			}
+
+			if structure, ok := frame.DropoffAt(x, y); ok {
+				marker = "E"
+				if structure.Owner == pid {
+					marker = "F"
+				}
+			}
+
+			if other, ok := frame.ShipAt(x, y); ok {
+				if other.Sid == ship.Sid {
+					marker = "@"
+				} else if other.Owner == pid {
+					marker = "S"
+				} else {
+					marker = "x"
+				}
+			}
+
+			cells = append(cells, fmt.Sprintf("%s%3d", marker, frame.HaliteAt(x, y) / 10))
+		}
+
+		lines = append(lines, strings.Join(cells, " "))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"./sim"
+)
+
+// An optional JSON version of the bot protocol, for bots given as json:<command>.
+// The engine works in the text protocol as always; this adapter sits in the
+// handler, translating what we send into JSON objects (one per line) and the
+// bot's JSON moves back into text. The bot gets:
+//
+//		{"type": "init", ...}		instead of the pregame
+//		{"type": "turn", ...}		instead of each update, with full entity lists
+//		{"type": "error", ...}		if its moves are rejected (the engine then kills it, as usual)
+//
+// It replies with its name (as plain text, or {"name": "..."}) then, each turn,
+// {"moves": [{"type": "m", "id": 3, "direction": "n"}, {"type": "g"}, {"type": "c", "id": 5}]}
+
+const JSON_PREFIX = "json:"
+const JSON_REJECTED = "?"						// Not a command, so the engine rejects it
+
+type JSONStructure struct {
+	Owner					int					`json:"owner"`
+	Id						int					`json:"id"`				// -1 for factories
+	X						int					`json:"x"`
+	Y						int					`json:"y"`
+}
+
+type JSONShip struct {
+	Id						int					`json:"id"`
+	X						int					`json:"x"`
+	Y						int					`json:"y"`
+	Halite					int					`json:"halite"`
+}
+
+type JSONPlayer struct {
+	Pid						int					`json:"pid"`
+	Budget					int					`json:"budget"`
+	Ships					[]JSONShip			`json:"ships"`
+	Dropoffs				[]JSONStructure		`json:"dropoffs"`		// Not including the factory
+}
+
+type JSONCell struct {
+	X						int					`json:"x"`
+	Y						int					`json:"y"`
+	Halite					int					`json:"halite"`
+}
+
+type JSONInit struct {
+	Type					string				`json:"type"`
+	Constants				*sim.Constants		`json:"constants"`
+	Players					int					`json:"players"`
+	Pid						int					`json:"pid"`
+	Width					int					`json:"width"`
+	Height					int					`json:"height"`
+	Factories				[]JSONStructure		`json:"factories"`
+	Halite					[][]int				`json:"halite"`			// Indexed [y][x], i.e. a list of rows
+}
+
+type JSONTurn struct {
+	Type					string				`json:"type"`
+	Turn					int					`json:"turn"`
+	Players					[]JSONPlayer		`json:"players"`
+	Cells					[]JSONCell			`json:"cells"`			// Only those that changed
+	Extended				*sim.ExtendedInfo	`json:"extended,omitempty"`	// Only for json:full: bots
+}
+
+type JSONError struct {
+	Type					string				`json:"type"`
+	Turn					int					`json:"turn"`
+	*sim.MoveError
+}
+
+type JSONMoves struct {
+	Name					string				`json:"name"`
+	Moves					[]sim.Move			`json:"moves"`
+}
+
+// -----------------------------------------------------------------------------------------
+
+type JSONAdapter struct {
+	pid						int
+	constants				*sim.Constants
+	frame					*sim.Frame			// As the bot sees it
+	extended				bool				// Updates have the extended part too
+}
+
+func (self *JSONAdapter) Pregame(pregame string) (string, error) {
+
+	var err error
+
+	self.frame, self.constants, err = sim.FrameFromPregameString(pregame)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Sscanf(strings.Split(pregame, "\n")[1], "%d %d", new(int), &self.pid)
+
+	init := JSONInit{
+		Type: "init",
+		Constants: self.constants,
+		Players: self.frame.Players(),
+		Pid: self.pid,
+		Width: self.frame.Width(),
+		Height: self.frame.Height(),
+	}
+
+	for pid := 0; pid < self.frame.Players(); pid++ {
+		factory := self.frame.Factory(pid)
+		init.Factories = append(init.Factories, JSONStructure{Owner: factory.Owner, Id: -1, X: factory.X, Y: factory.Y})
+	}
+
+	for y := 0; y < self.frame.Height(); y++ {
+		var row []int
+		for x := 0; x < self.frame.Width(); x++ {
+			row = append(row, self.frame.HaliteAt(x, y))
+		}
+		init.Halite = append(init.Halite, row)
+	}
+
+	b, _ := json.Marshal(init)
+	return string(b), nil
+}
+
+func PregameFromJSONInit(line string) (string, bool) {
+
+	// The reverse of Pregame(), for reading a json: bot's transcript. Returns false
+	// if the line isn't an init object (e.g. it's the constants of a text pregame).
+
+	var init JSONInit
+
+	if json.Unmarshal([]byte(line), &init) != nil || init.Type != "init" || init.Constants == nil {
+		return "", false
+	}
+
+	json_blob, _ := json.Marshal(init.Constants)
+
+	lines := []string{string(json_blob), fmt.Sprintf("%d %d", init.Players, init.Pid)}
+
+	for _, factory := range init.Factories {
+		lines = append(lines, fmt.Sprintf("%d %d %d", factory.Owner, factory.X, factory.Y))
+	}
+
+	lines = append(lines, fmt.Sprintf("%d %d", init.Width, init.Height))
+
+	for _, row := range init.Halite {
+		var elements []string
+		for _, val := range row {
+			elements = append(elements, strconv.Itoa(val))
+		}
+		lines = append(lines, strings.Join(elements, " "))
+	}
+
+	return strings.Join(lines, "\n"), true
+}
+
+func (self *JSONAdapter) Update(update string) (string, error) {
+
+	old := self.frame
+
+	frame, err := sim.FrameFromUpdate(old, update)
+	if err != nil {
+		return "", err
+	}
+
+	self.frame = frame
+
+	turn := JSONTurn{
+		Type: "turn",
+		Turn: frame.Turn(),
+		Cells: make([]JSONCell, 0),
+	}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+
+		player := JSONPlayer{
+			Pid: pid,
+			Budget: frame.Budget(pid),
+			Ships: make([]JSONShip, 0),
+			Dropoffs: make([]JSONStructure, 0),
+		}
+
+		for _, ship := range frame.ShipsOf(pid) {
+			player.Ships = append(player.Ships, JSONShip{Id: ship.Sid, X: ship.X, Y: ship.Y, Halite: ship.Halite})
+		}
+
+		for _, dropoff := range frame.DropoffsOf(pid) {
+			if dropoff.Factory == false {
+				player.Dropoffs = append(player.Dropoffs, JSONStructure{Owner: pid, Id: dropoff.Sid, X: dropoff.X, Y: dropoff.Y})
+			}
+		}
+
+		turn.Players = append(turn.Players, player)
+	}
+
+	for x := 0; x < frame.Width(); x++ {
+		for y := 0; y < frame.Height(); y++ {
+			if frame.HaliteAt(x, y) != old.HaliteAt(x, y) {
+				turn.Cells = append(turn.Cells, JSONCell{X: x, Y: y, Halite: frame.HaliteAt(x, y)})
+			}
+		}
+	}
+
+	if self.extended {
+		turn.Extended, err = sim.ExtendedInfoFromUpdate(update, frame.Players())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	b, _ := json.Marshal(turn)
+	return string(b), nil
+}
+
+func (self *JSONAdapter) Name(line string) string {
+
+	// The name line can be plain text or {"name": "..."}
+
+	var reply JSONMoves
+
+	if json.Unmarshal([]byte(line), &reply) == nil && reply.Name != "" {
+		return reply.Name
+	}
+
+	return line
+}
+
+func (self *JSONAdapter) Moves(line string) (string, []string) {
+
+	// Returns the moves as text for the engine, and any error objects for the bot.
+	// The moves are checked with the engine's rules, on the state the bot was sent,
+	// so the bot can be told exactly what the engine didn't like.
+	//
+	// Any moves we find fault with become JSON_REJECTED, which the engine is sure to
+	// reject, so the bot is kicked just as its error object says. (Passing them on
+	// would let through e.g. a direction of "e m 4 w", or a line of "g", as moves.)
+
+	var reply JSONMoves
+
+	err := json.Unmarshal([]byte(line), &reply)
+	if err != nil {
+		return JSON_REJECTED, []string{self.error_line(&sim.MoveError{
+			Pid: self.pid,
+			Index: -1,
+			Sid: -1,
+			Kind: "bad_json",
+			Message: err.Error(),
+		})}
+	}
+
+	game := sim.NewGame(self.constants)
+	game.UseFrame(self.frame.Copy())
+
+	all_moves := make([][]sim.Move, self.frame.Players())
+	all_moves[self.pid] = reply.Moves
+
+	_, _, errors := game.Simulate(all_moves)
+
+	if len(errors) > 0 {
+		var error_lines []string
+		for _, e := range errors {
+			error_lines = append(error_lines, self.error_line(e))
+		}
+		return JSON_REJECTED, error_lines
+	}
+
+	return sim.MovesString(reply.Moves), nil
+}
+
+func (self *JSONAdapter) error_line(e *sim.MoveError) string {
+	b, _ := json.Marshal(JSONError{Type: "error", Turn: self.frame.Turn(), MoveError: e})
+	return string(b)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"./sim"
+)
+
+func TestJSONMoves(t *testing.T) {
+
+	constants := sim.NewConstants(2, 32, 32, 400, 42)
+	game := sim.NewGame(constants)
+	game.UseFrame(sim.MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42))
+
+	adapter := new(JSONAdapter)
+
+	if _, err := adapter.Pregame(game.PregameString(0)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	update, _ := game.UpdateFromMoves([]string{"g", "g"})			// Player 0 now has ship 0
+
+	if _, err := adapter.Update(update); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	tests := []struct {
+		line		string
+		moves		string
+		errors		int
+	}{
+		{`{"moves": [{"type": "m", "id": 0, "direction": "e"}, {"type": "g"}]}`, "m 0 e g", 0},
+		{`{"moves": []}`, "", 0},
+		{`{"moves": [{"type": "m", "id": 0, "direction": "e m 1 w"}]}`, JSON_REJECTED, 1},
+		{`{"moves": [{"type": "m", "id": 0}, {"type": "m", "id": 0, "direction": "n"}]}`, JSON_REJECTED, 1},
+		{`{"moves": [{"type": "m", "id": 1, "direction": "n"}]}`, JSON_REJECTED, 1},
+		{`{"moves": [{"type": "g m 0 n"}]}`, JSON_REJECTED, 1},
+		{`g`, JSON_REJECTED, 1},
+		{``, JSON_REJECTED, 1},
+	}
+
+	for _, test := range tests {
+		moves, error_lines := adapter.Moves(test.line)
+		if moves != test.moves || len(error_lines) != test.errors {
+			t.Errorf("Moves(%q): got %q and %d errors, expected %q and %d", test.line, moves, len(error_lines), test.moves, test.errors)
+		}
+	}
+}
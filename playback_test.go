@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"./sim"
+)
+
+func TestLoadRecording(t *testing.T) {
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "t-p0.out")
+
+	ioutil.WriteFile(filename, []byte(`{"turn":0,"line":"MyBot"}
+{"turn":1,"line":"g"}
+{"turn":2,"line":"","timeout":true}
+{"turn":3,"line":""}
+`), 0644)
+
+	recording, err := LoadRecording(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(recording.Lines, map[int]string{0: "MyBot", 1: "g", 3: ""}) == false {
+		t.Errorf("Lines: got %v", recording.Lines)
+	}
+
+	if reflect.DeepEqual(recording.Timeouts, map[int]bool{2: true}) == false {
+		t.Errorf("Timeouts: got %v", recording.Timeouts)
+	}
+
+	ioutil.WriteFile(filename, []byte(`{"turn":0,"line":"MyBot"}` + "\n" + `{"turn":1,"li`), 0644)
+
+	if _, err := LoadRecording(filename); err == nil {
+		t.Errorf("No error from a line cut short")
+	}
+
+	if _, err := LoadRecording(filepath.Join(dir, "missing.out")); err == nil {
+		t.Errorf("No error from a missing file")
+	}
+}
+
+func TestPlaybackPregame(t *testing.T) {
+
+	// Either kind of pregame, text or JSON, should give back the map the game was made with.
+
+	constants := sim.NewConstants(2, 32, 32, 400, 42)
+	game := sim.NewGame(constants)
+	game.UseFrame(sim.MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42))
+
+	pregame := game.PregameString(1)
+
+	json_init, err := new(JSONAdapter).Pregame(pregame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	for name, contents := range map[string]string{"text": pregame, "json": json_init} {
+
+		filename := filepath.Join(dir, name + "-p0.in")
+		ioutil.WriteFile(filename, []byte(contents + "\n1\n"), 0644)			// Plus the start of an update
+
+		frame, got_constants, err := playback_pregame(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if frame.Snapshot() != game.Frame().Snapshot() {
+			t.Errorf("%s: got a different frame", name)
+		}
+
+		if reflect.DeepEqual(*got_constants, *constants) == false {
+			t.Errorf("%s: got different constants", name)
+		}
+	}
+}
+
+func TestPlayback(t *testing.T) {
+
+	// Playing back a recorded game should reproduce it exactly.
+
+	dir := t.TempDir()
+	ps, base := recorded_game(t, dir, "builtin:greedy", "builtin:returner")
+
+	out := run_dubnium(t, dir, "--playback", base, "--replay-directory", dir)
+
+	playback := new(PrintedStats)
+	if err := json.Unmarshal([]byte(out), playback); err != nil {
+		t.Fatal(err)
+	}
+
+	if playback.MapSeed != ps.MapSeed || playback.MapHalite != ps.MapHalite || playback.FinalSnapshot != ps.FinalSnapshot {
+		t.Errorf("Playback ended differently")
+	}
+
+	for pid := 0; pid < 2; pid++ {
+		if playback.Stats[pid].Rank != ps.Stats[pid].Rank || playback.Stats[pid].Score != ps.Stats[pid].Score {
+			t.Errorf("Player %d: got %+v, expected %+v", pid, playback.Stats[pid], ps.Stats[pid])
+		}
+	}
+}
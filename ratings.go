@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"./trueskill"
+)
+
+func print_ratings(filename string) {
+
+	// Rate every game in a results store, in the order played, like the ladder did.
+
+	results, err := load_results(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+
+	env := trueskill.NewEnv()
+
+	ratings := make(map[string]trueskill.Rating)
+	games := make(map[string]int)
+
+	for _, result := range results {
+
+		var before []trueskill.Rating
+		var ranks []int
+
+		for _, player := range result.Players {
+
+			if _, ok := ratings[player.Cmd]; ok == false {
+				ratings[player.Cmd] = trueskill.NewRating()
+			}
+
+			before = append(before, ratings[player.Cmd])
+			ranks = append(ranks, player.Rank)
+		}
+
+		after := env.Rate(before, ranks)
+
+		for n, player := range result.Players {
+			ratings[player.Cmd] = after[n]
+			games[player.Cmd]++
+		}
+	}
+
+	var bots []string
+	for bot := range ratings {
+		bots = append(bots, bot)
+	}
+
+	sort.Slice(bots, func(a, b int) bool {
+		return ratings[bots[a]].Exposure() > ratings[bots[b]].Exposure()
+	})
+
+	fmt.Printf("%4s  %7s  %7s  %7s  %6s  %s\n", "Rank", "Score", "Mu", "Sigma", "Games", "Bot")
+
+	for n, bot := range bots {
+		r := ratings[bot]
+		fmt.Printf("%4d  %7.2f  %7.2f  %7.2f  %6d  %s\n", n + 1, r.Exposure(), r.Mu, r.Sigma, games[bot], bot)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Seats given as "remote" are filled by bots connecting to us, e.g. from inside a
+// debugger, rather than by programs we start. They speak exactly the same line
+// protocol over the connection as other bots do over stdin / stdout.
+
+const REMOTE_BOT = "remote"
+
+func accept_remotes(listen string, n int) ([]net.Conn, error) {
+
+	// Waits for n bots to connect. The listen address is tcp:<host:port> or
+	// unix:<path>. Seats are given out in the order the bots connect.
+
+	parts := strings.SplitN(listen, ":", 2)
+
+	if len(parts) != 2 || (parts[0] != "tcp" && parts[0] != "unix") {
+		return nil, fmt.Errorf("Couldn't understand --listen %s (want tcp:<host:port> or unix:<path>)", listen)
+	}
+
+	listener, err := net.Listen(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()			// Also removes the socket file, for unix
+
+	fmt.Fprintf(os.Stderr, "Waiting for %d bot(s) to connect to %s\n", n, listener.Addr())
+
+	var conns []net.Conn
+
+	for len(conns) < n {
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(os.Stderr, "Bot connected from %s\n", conn.RemoteAddr())
+		conns = append(conns, conn)
+	}
+
+	return conns, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"./hlt"
+)
+
+func dial_when_ready(network, address string) (net.Conn, error) {
+
+	// The engine may not be listening yet.
+
+	var err error
+
+	for tries := 0; tries < 100; tries++ {
+		var conn net.Conn
+		conn, err = net.Dial(network, address)
+		if err == nil {
+			return conn, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return nil, err
+}
+
+func TestAcceptRemotes(t *testing.T) {
+
+	address := filepath.Join(t.TempDir(), "dubnium.sock")
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			conn, err := dial_when_ready("unix", address)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(conn, "%d\n", i)		// Dial() has returned, so we're queued before the next one
+		}
+	}()
+
+	conns, err := accept_remotes("unix:" + address, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, conn := range conns {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line != fmt.Sprintf("%d\n", i) {
+			t.Errorf("Connection %d was client %q", i, line)
+		}
+		conn.Close()
+	}
+
+	for _, listen := range []string{"127.0.0.1:5000", "udp:127.0.0.1:5000", "unix"} {
+		if _, err := accept_remotes(listen, 1); err == nil {
+			t.Errorf("No error from --listen %s", listen)
+		}
+	}
+}
+
+func TestRemoteSeats(t *testing.T) {
+
+	// Remote seats are given out in the order the bots connect, skipping the other seats.
+
+	dir := t.TempDir()
+	address := filepath.Join(dir, "dubnium.sock")
+
+	pids := []chan int{make(chan int, 1), make(chan int, 1)}		// By order of connection
+
+	go func() {
+
+		var conns []net.Conn
+
+		for i := 0; i < 2; i++ {				// All must connect before the engine sends anything
+			conn, err := dial_when_ready("unix", address)
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+
+		for i, conn := range conns {
+			go func(conn net.Conn, pids chan int) {		// Play as an idle bot
+				defer conn.Close()
+				game, err := hlt.NewGame(conn, conn)
+				if err != nil {
+					pids <- -1
+					return
+				}
+				pids <- game.Pid
+				game.Ready("Remote")
+				for game.Update() == nil {
+					game.Send(nil)
+				}
+			}(conn, pids[i])
+		}
+	}()
+
+	run_dubnium(t, dir, "--listen", "unix:" + address, "--width", "32", "--height", "32", "--no-replay", "remote", "builtin:idle", "remote")
+
+	if first, second := <-pids[0], <-pids[1]; first != 0 || second != 2 {
+		t.Errorf("The remote bots got seats %d and %d, expected 0 and 2", first, second)
+	}
+}
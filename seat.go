@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+)
+
+// A bot command can have prefixes saying how to talk to it, in any order, e.g.
+// json:full:python3 bot.py
+
+const EXTENDED_PREFIX = "full:"
+
+type Seat struct {
+	Cmd							string
+	JSON						bool			// Speaks the JSON protocol (json_protocol.go)
+	Extended					bool			// Gets extended updates (sim/extended.go)
+}
+
+func parse_seat(cmd string) Seat {
+
+	var ret Seat
+
+	for {
+		if strings.HasPrefix(cmd, JSON_PREFIX) {
+			cmd = strings.TrimPrefix(cmd, JSON_PREFIX)
+			ret.JSON = true
+		} else if strings.HasPrefix(cmd, EXTENDED_PREFIX) {
+			cmd = strings.TrimPrefix(cmd, EXTENDED_PREFIX)
+			ret.Extended = true
+		} else {
+			break
+		}
+	}
+
+	ret.Cmd = cmd
+	return ret
+}
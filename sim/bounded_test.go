@@ -0,0 +1,43 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestBounded(t *testing.T) {
+
+	constants := NewConstants(2, 48, 48, 400, 42)
+	constants.BOUNDED = true
+
+	// Ship 0 tries to go off the west edge, so stays and mines. Ship 1 would
+	// inspire ship 0 from across the edge, if the map wrapped...
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 0, Y: 5, Halite: 100},
+		{Owner: 1, Sid: 1, X: 47, Y: 5},
+		{Owner: 1, Sid: 2, X: 46, Y: 5},
+	}
+
+	frame := test_frame(t, MapGenBounded(2, 48, 48, constants.INITIAL_ENERGY, 42), ships, map[Position]int{{0, 5}: 400})
+	game := test_game(constants, frame)
+
+	if f := frame.Factory(0); f.X != 12 || f.Y != 24 {
+		t.Errorf("Factory 0 at %d %d, expected the middle of its tile", f.X, f.Y)
+	}
+
+	game.UpdateFromMoves([]string{"m 0 w", ""})
+
+	ship, _ := game.Frame().Ship(0)
+
+	if ship.X != 0 || ship.Y != 5 || ship.Halite != 200 {
+		t.Errorf("Got ship %+v, expected it to stay and mine 100", ship)
+	}
+
+	if ship.Inspired {
+		t.Errorf("Ship was inspired from across the edge")
+	}
+
+	if _, ok := game.ShipAt(48, 5); ok || game.HaliteAt(-1, 5) != 0 {
+		t.Errorf("Game accessors wrapped on a bounded map")
+	}
+}
@@ -33,6 +33,14 @@ type Constants struct {
 	STRICT_ERRORS				bool
 
 	GameSeed					uint32				`json:"game_seed"`		// Sent to bots but (in official) not to replay.
+
+	// Variants. These are left out of the JSON when off, so normal games look official.
+
+	RULES						string				`json:"RULES,omitempty"`			// See rules.go
+	BOUNDED						bool				`json:"BOUNDED,omitempty"`		// Edges are walls rather than wrapping round
+	FOG_RADIUS					int					`json:"FOG_RADIUS,omitempty"`	// See fog.go
+	REGROWTH_RATIO				int					`json:"REGROWTH_RATIO,omitempty"`		// Each turn, cells regain 1 / this of what they're missing...
+	REGROWTH_TARGET				int					`json:"REGROWTH_TARGET,omitempty"`	// ...compared to this percentage of their initial halite
 }
 
 func NewConstants(players, width, height, turns int, seed uint32) *Constants {
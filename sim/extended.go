@@ -0,0 +1,254 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Extended updates, for bots that opt in. These are the normal update followed by
+// things the normal protocol leaves out, but which the replay has anyway:
+//
+//		<deposited by player 0> ... <deposited by player n-1>
+//		<count> <sid> <sid> ...					(the inspired ships)
+//		<event count>
+//		spawn <sid> <owner> <x> <y>
+//		construct <sid> <owner> <x> <y>
+//		shipwreck <x> <y> <sid> <sid> ...
+//
+// The events are those of the turn just played, i.e. the ones in its ReplayFrame.
+
+type ExtendedInfo struct {
+	Deposited				[]int						`json:"deposited"`
+	Inspired				[]int						`json:"inspired"`
+	Events					[]*ReplayEvent				`json:"events"`
+}
+
+func (self *Game) ExtendedInfo(rf *ReplayFrame) *ExtendedInfo {
+
+	// For the current frame. The ReplayFrame of the turn that made it can be nil,
+	// e.g. when starting from a snapshot, in which case there are no events.
+
+	ret := &ExtendedInfo{
+		Deposited: make([]int, 0),
+		Inspired: make([]int, 0),
+		Events: make([]*ReplayEvent, 0),
+	}
+
+	for pid := 0; pid < self.frame.Players(); pid++ {
+		ret.Deposited = append(ret.Deposited, self.frame.deposited[pid])
+	}
+
+	for _, ship := range self.frame.ships {
+		if ship != nil && ship.Inspired {
+			ret.Inspired = append(ret.Inspired, ship.Sid)
+		}
+	}
+
+	if rf != nil {
+		ret.Events = append(ret.Events, rf.Events...)
+	}
+
+	return ret
+}
+
+func (self *Game) ExtendedUpdateString(update string, rf *ReplayFrame) string {
+
+	// update should be what UpdateFromMoves() or CurrentUpdateString() just returned.
+
+	return update + "\n" + self.ExtendedInfo(rf).String()
+}
+
+func (self *ExtendedInfo) String() string {
+
+	var lines []string
+
+	var deposited []string
+	for _, n := range self.Deposited {
+		deposited = append(deposited, strconv.Itoa(n))
+	}
+	lines = append(lines, strings.Join(deposited, " "))
+
+	inspired := []string{strconv.Itoa(len(self.Inspired))}
+	for _, sid := range self.Inspired {
+		inspired = append(inspired, strconv.Itoa(sid))
+	}
+	lines = append(lines, strings.Join(inspired, " "))
+
+	lines = append(lines, strconv.Itoa(len(self.Events)))
+
+	for _, event := range self.Events {
+		switch event.Type {
+		case "shipwreck":
+			fields := []string{"shipwreck", strconv.Itoa(event.Location.X), strconv.Itoa(event.Location.Y)}
+			for _, sid := range event.WreckedSids {
+				fields = append(fields, strconv.Itoa(sid))
+			}
+			lines = append(lines, strings.Join(fields, " "))
+		default:
+			lines = append(lines, fmt.Sprintf("%s %d %d %d %d", event.Type, event.Sid, event.Owner, event.Location.X, event.Location.Y))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func ReadExtendedInfo(next_line func() (string, error), players int) (*ExtendedInfo, error) {
+
+	// Reads the extended part of an update, a line at a time, from whatever source
+	// the caller has (the normal part having been read already).
+
+	ret := &ExtendedInfo{
+		Deposited: make([]int, 0),
+		Inspired: make([]int, 0),
+		Events: make([]*ReplayEvent, 0),
+	}
+
+	next_fields := func() ([]string, error) {
+		line, err := next_line()
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(line), nil
+	}
+
+	atois := func(fields []string) ([]int, error) {
+		var ret []int
+		for _, field := range fields {
+			val, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("ReadExtendedInfo: %v", err)
+			}
+			ret = append(ret, val)
+		}
+		return ret, nil
+	}
+
+	fields, err := next_fields()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != players {
+		return nil, fmt.Errorf("ReadExtendedInfo: wanted %d deposited totals, got %d", players, len(fields))
+	}
+	ret.Deposited, err = atois(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err = next_fields()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := atois(fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) == 0 || len(vals) != vals[0] + 1 {
+		return nil, fmt.Errorf("ReadExtendedInfo: bad inspired line")
+	}
+	ret.Inspired = append(ret.Inspired, vals[1:]...)
+
+	fields, err = next_fields()
+	if err != nil {
+		return nil, err
+	}
+	vals, err = atois(fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != 1 {
+		return nil, fmt.Errorf("ReadExtendedInfo: bad event count")
+	}
+
+	for i := 0; i < vals[0]; i++ {
+
+		fields, err = next_fields()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("ReadExtendedInfo: bad event")
+		}
+		ints, err := atois(fields[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		switch fields[0] {
+		case "shipwreck":
+			ret.Events = append(ret.Events, &ReplayEvent{
+				Type: "shipwreck",
+				Location: &Position{ints[0], ints[1]},
+				WreckedSids: ints[2:],
+			})
+		case "spawn", "construct":
+			if len(ints) != 4 {
+				return nil, fmt.Errorf("ReadExtendedInfo: bad %s event", fields[0])
+			}
+			ret.Events = append(ret.Events, &ReplayEvent{
+				Type: fields[0],
+				Sid: ints[0],
+				Owner: ints[1],
+				Location: &Position{ints[2], ints[3]},
+			})
+		default:
+			return nil, fmt.Errorf("ReadExtendedInfo: unknown event \"%s\"", fields[0])
+		}
+	}
+
+	return ret, nil
+}
+
+func ExtendedInfoFromUpdate(update string, players int) (*ExtendedInfo, error) {
+
+	// Skips the normal part of an extended update, and reads the rest.
+
+	scanner := bufio.NewScanner(strings.NewReader(update))
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	next_ints := int_line_reader(scanner, "ExtendedInfoFromUpdate")
+
+	skip := func(n int) error {
+		for i := 0; i < n; i++ {
+			if scanner.Scan() == false {
+				return fmt.Errorf("ExtendedInfoFromUpdate: unexpected end of input")
+			}
+		}
+		return nil
+	}
+
+	err := skip(1)								// Turn
+	if err != nil {
+		return nil, err
+	}
+
+	for n := 0; n < players; n++ {
+		player_line, err := next_ints(4)
+		if err != nil {
+			return nil, err
+		}
+		err = skip(player_line[1] + player_line[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	count_line, err := next_ints(1)
+	if err != nil {
+		return nil, err
+	}
+
+	err = skip(count_line[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadExtendedInfo(func() (string, error) {
+		if scanner.Scan() == false {
+			return "", fmt.Errorf("ExtendedInfoFromUpdate: unexpected end of input")
+		}
+		return scanner.Text(), nil
+	}, players)
+}
@@ -0,0 +1,66 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtendedUpdates(t *testing.T) {
+
+	// One turn with one of everything: ship 0 deposits 500, ship 1 is inspired by
+	// ships 3 and 4, ship 2 builds a dropoff, player 1 spawns, and ships 5 and 6
+	// crash into each other. The cells moved from are emptied, so moves are free.
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 9, Y: 16, Halite: 500},
+		{Owner: 0, Sid: 1, X: 5, Y: 5},
+		{Owner: 0, Sid: 2, X: 12, Y: 28},
+		{Owner: 1, Sid: 3, X: 5, Y: 7},
+		{Owner: 1, Sid: 4, X: 6, Y: 5},
+		{Owner: 1, Sid: 5, X: 20, Y: 28},
+		{Owner: 1, Sid: 6, X: 22, Y: 28},
+	}
+
+	cells := map[Position]int{{9, 16}: 0, {12, 28}: 0, {20, 28}: 0, {22, 28}: 0}
+
+	constants := NewConstants(2, 32, 32, 400, 42)
+	game := test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, cells))
+
+	update, rf := game.UpdateFromMoves([]string{"m 0 w c 2", "g m 5 e m 6 w"})
+
+	got, err := ExtendedInfoFromUpdate(game.ExtendedUpdateString(update, rf), 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if reflect.DeepEqual(got.Deposited, []int{500, 0}) == false {
+		t.Errorf("Got deposited %v, expected [500 0]", got.Deposited)
+	}
+
+	if reflect.DeepEqual(got.Inspired, []int{1}) == false {
+		t.Errorf("Got inspired %v, expected [1]", got.Inspired)
+	}
+
+	expected := map[string]ReplayEvent{
+		"spawn":		{Type: "spawn", Owner: 1, Location: &Position{X: 23, Y: 16}},
+		"construct":	{Type: "construct", Owner: 0, Sid: 2, Location: &Position{X: 12, Y: 28}},
+		"shipwreck":	{Type: "shipwreck", Location: &Position{X: 21, Y: 28}, WreckedSids: []int{5, 6}},
+	}
+
+	if len(got.Events) != len(expected) {
+		t.Fatalf("Got %d events, expected %d", len(got.Events), len(expected))
+	}
+
+	for _, event := range got.Events {
+		want, ok := expected[event.Type]
+		if ok == false || event.Owner != want.Owner || *event.Location != *want.Location {
+			t.Errorf("Unexpected %s event: %+v at %+v", event.Type, event, event.Location)
+		}
+		if event.Type == "construct" && event.Sid != want.Sid {
+			t.Errorf("Dropoff built by ship %d, expected %d", event.Sid, want.Sid)
+		}
+		if event.Type == "shipwreck" && reflect.DeepEqual(event.WreckedSids, want.WreckedSids) == false {
+			t.Errorf("Wrecked ships %v, expected %v", event.WreckedSids, want.WreckedSids)
+		}
+	}
+}
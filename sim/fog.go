@@ -0,0 +1,87 @@
+package sim
+
+// Fog of war, a variant where each bot only sees the cells and enemy ships within
+// Constants.FOG_RADIUS of its own ships and structures. Each player has their own
+// update string, which only sends the visible cells that differ from what they last
+// saw there. The replay, and the update string returned by UpdateFromMoves(), still
+// have everything.
+//
+// Bots still get the whole map in the pregame, and everyone's budgets and dropoffs.
+
+func (self *Game) Fogged() bool {
+	return self.Constants.FOG_RADIUS > 0
+}
+
+func (self *Game) PlayerUpdateString(pid int, update string) string {
+
+	// What to actually send to player pid, given the update just made. With fog of
+	// war, this is their own view of the turn; otherwise it's the update itself.
+
+	if self.player_updates == nil {
+		return update
+	}
+
+	return self.player_updates[pid]
+}
+
+func (self *Game) make_player_updates(old, current *Frame) {
+
+	if self.Fogged() == false {
+		self.player_updates = nil
+		return
+	}
+
+	players := current.Players()
+	width := current.Width()
+	height := current.Height()
+
+	// The first time, the players know what they were sent in the pregame, i.e. the
+	// halite of the frame we started with...
+
+	if self.fog_known == nil {
+		for pid := 0; pid < players; pid++ {
+			known := make_2d_int_array(width, height)
+			for x := 0; x < width; x++ {
+				copy(known[x], old.halite[x])
+			}
+			self.fog_known = append(self.fog_known, known)
+		}
+	}
+
+	self.player_updates = make([]string, players)
+
+	for pid := 0; pid < players; pid++ {
+
+		visible := fog_visibility(current, pid, self.Constants.FOG_RADIUS, self.Constants.BOUNDED)
+
+		self.player_updates[pid] = make_update_string(current, self.fog_known[pid], visible, pid)
+
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				if visible[x][y] {
+					self.fog_known[pid][x][y] = current.halite[x][y]
+				}
+			}
+		}
+	}
+}
+
+func PlayerUpdateStrings(constants *Constants, frames []*Frame, pid int) []string {
+
+	// Rebuilds what player pid was sent after each turn of a game, from its frames
+	// (e.g. from a replay). Element n is the update made from frames[n] and frames[n + 1].
+	// Fog of war needs this, since what a bot knows depends on everything it saw before.
+
+	game := NewGame(constants)
+	game.UseFrame(frames[0])
+
+	var ret []string
+
+	for n := 0; n + 1 < len(frames); n++ {
+		update := make_bot_update_string(frames[n], frames[n + 1])
+		game.make_player_updates(frames[n], frames[n + 1])
+		ret = append(ret, game.PlayerUpdateString(pid, update))
+	}
+
+	return ret
+}
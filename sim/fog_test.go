@@ -0,0 +1,76 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestFogOfWar(t *testing.T) {
+
+	// Player 0's ship is 3 from ship 1, which it can see, and 4 from ship 2, which
+	// it can't. Both of player 1's ships mine, so player 0 should only hear about
+	// the first cell, until it moves closer to the second.
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 5, Y: 5},
+		{Owner: 1, Sid: 1, X: 5, Y: 8},
+		{Owner: 1, Sid: 2, X: 5, Y: 9},
+	}
+
+	cells := map[Position]int{{5, 5}: 0, {5, 8}: 400, {5, 9}: 400}
+
+	constants := NewConstants(2, 32, 32, 400, 42)
+	constants.FOG_RADIUS = 3
+
+	game := test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, cells))
+
+	view, _, err := FrameFromPregameString(game.PregameString(0))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	before := game.Frame()
+	update, _ := game.UpdateFromMoves([]string{"", ""})
+
+	if view, err = FrameFromUpdate(view, game.PlayerUpdateString(0, update)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, ok := view.Ship(1); ok == false {
+		t.Errorf("Ship 1 hidden, though 3 away")
+	}
+
+	if _, ok := view.Ship(2); ok {
+		t.Errorf("Ship 2 seen, though 4 away")
+	}
+
+	if view.HaliteAt(5, 8) != 300 || view.HaliteAt(5, 9) != 400 {
+		t.Errorf("Player 0 sees %d and %d, expected 300 and 400", view.HaliteAt(5, 8), view.HaliteAt(5, 9))
+	}
+
+	// Player 1 isn't fogged from its own ships, and the normal update still has everything...
+
+	if other, err := FrameFromUpdate(before, game.PlayerUpdateString(1, update)); err != nil || len(other.ShipsOf(1)) != 2 {
+		t.Errorf("Player 1 can't see its own ships")
+	}
+
+	if full, err := FrameFromUpdate(before, update); err != nil || full.HaliteAt(5, 9) != 300 {
+		t.Errorf("Full update lost the hidden cell")
+	}
+
+	// Moving south brings ship 2 into view, and its cell, which has been mined twice
+	// since player 0 last saw it...
+
+	update, _ = game.UpdateFromMoves([]string{"m 0 s", ""})
+
+	if view, err = FrameFromUpdate(view, game.PlayerUpdateString(0, update)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, ok := view.Ship(2); ok == false {
+		t.Errorf("Ship 2 hidden after moving closer")
+	}
+
+	if view.HaliteAt(5, 9) != 225 {
+		t.Errorf("Player 0 sees %d at 5 9, expected 225", view.HaliteAt(5, 9))
+	}
+}
@@ -1,10 +1,18 @@
 package sim
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"image/png"
+	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 
@@ -24,14 +32,19 @@ func FrameFromFile(infile string) (*Frame, uint32) {
 	}
 	defer f.Close()
 
-	bytes, err := ioutil.ReadAll(f)
+	raw, err := ioutil.ReadAll(f)
 	if err != nil {
 		panic("Couldn't read infile")
 	}
 
+	raw, err = decompress_if_needed(raw)
+	if err != nil {
+		panic("Couldn't decompress infile")
+	}
+
 	foo := new(StuffWeWant)
 
-	err = json.Unmarshal(bytes, foo)
+	err = json.Unmarshal(raw, foo)
 	if err != nil {
 		panic("Couldn't parse infile")
 	}
@@ -104,10 +117,15 @@ func FrameFromPNG(infile string) *Frame {
 	for x := 0; x < width; x++ {
 		for y := 0; y < height; y++ {
 
-			r, g, b, _ := img.At(x, y).RGBA()		// rgb will be values up to 65535
+			// Grey is halite and any other colour is a factory, as always. A fully
+			// transparent pixel (which used to be 0 halite) is an obstacle.
+
+			r, g, b, a := img.At(x, y).RGBA()		// rgba will be values up to 65535
 			frame.halite[x][y] = int(r / 65)
 
-			if r != g || r != b {
+			if a == 0 {
+				frame.halite[x][y] = OBSTACLE
+			} else if r != g || r != b {
 				factory_locations = append(factory_locations, Position{x, y})
 			}
 		}
@@ -142,3 +160,230 @@ func FrameFromPNG(infile string) *Frame {
 	return frame
 }
 
+var zstd_magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+func decompress_if_needed(raw []byte) ([]byte, error) {
+
+	// Official replays are zstd compressed, ours may or may not be.
+	// Anything without the zstd magic number is assumed to be JSON.
+
+	if bytes.HasPrefix(raw, zstd_magic) == false {
+		return raw, nil
+	}
+
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	return dec.DecodeAll(raw, nil)
+}
+
+func FrameFromPregame(infile string) (*Frame, *Constants, error) {
+
+	// Reads the start of a bot's input stream, e.g. a transcript .in file,
+	// which holds everything needed to recreate the initial frame.
+
+	f, err := os.Open(infile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return frame_from_pregame(f)
+}
+
+func FrameFromPregameString(pregame string) (*Frame, *Constants, error) {
+	return frame_from_pregame(strings.NewReader(pregame))
+}
+
+func frame_from_pregame(r io.Reader) (*Frame, *Constants, error) {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	next_ints := int_line_reader(scanner, "FrameFromPregame")
+
+	if scanner.Scan() == false {
+		return nil, nil, fmt.Errorf("FrameFromPregame: no constants")
+	}
+
+	constants := new(Constants)
+
+	err := json.Unmarshal(scanner.Bytes(), constants)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	player_line, err := next_ints(2)		// Player count, and the pid of the bot, which we don't care about
+	if err != nil {
+		return nil, nil, err
+	}
+
+	players := player_line[0]
+
+	frame := new(Frame)
+
+	for pid := 0; pid < players; pid++ {
+		frame.budgets = append(frame.budgets, constants.INITIAL_ENERGY)
+		frame.deposited = append(frame.deposited, 0)
+		frame.last_alive = append(frame.last_alive, -1)
+	}
+
+	for pid := 0; pid < players; pid++ {
+
+		factory_line, err := next_ints(3)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		frame.dropoffs = append(frame.dropoffs, &Dropoff{
+			Factory: true,
+			Owner: factory_line[0],
+			Sid: -1,
+			X: factory_line[1],
+			Y: factory_line[2],
+			Gathered: 0,
+		})
+	}
+
+	size_line, err := next_ints(2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	width, height := size_line[0], size_line[1]
+
+	frame.halite = make_2d_int_array(width, height)
+
+	for y := 0; y < height; y++ {
+
+		row, err := next_ints(width)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for x := 0; x < width; x++ {
+			frame.halite[x][y] = row[x]
+		}
+	}
+
+	return frame, constants, nil
+}
+
+func FrameFromUpdate(old *Frame, update string) (*Frame, error) {
+
+	// The reverse of make_bot_update_string(), i.e. the frame as a bot sees it. Some
+	// things bots aren't sent are missing: inspiration, deposits, dropoff totals,
+	// and who is dead.
+
+	scanner := bufio.NewScanner(strings.NewReader(update))
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	next_ints := int_line_reader(scanner, "FrameFromUpdate")
+
+	frame := old.Copy()
+
+	turn_line, err := next_ints(1)
+	if err != nil {
+		return nil, err
+	}
+
+	frame.turn = turn_line[0]
+	frame.ships = nil
+	frame.dropoffs = frame.dropoffs[:frame.Players()]		// Just the factories
+
+	for n := 0; n < frame.Players(); n++ {
+
+		player_line, err := next_ints(4)
+		if err != nil {
+			return nil, err
+		}
+
+		pid := player_line[0]
+
+		if pid < 0 || pid >= frame.Players() {
+			return nil, fmt.Errorf("FrameFromUpdate: bad pid %d", pid)
+		}
+
+		frame.budgets[pid] = player_line[3]
+
+		for i := 0; i < player_line[1]; i++ {
+
+			ship_line, err := next_ints(4)
+			if err != nil {
+				return nil, err
+			}
+
+			sid := ship_line[0]
+
+			if sid < 0 {
+				return nil, fmt.Errorf("FrameFromUpdate: bad sid %d", sid)
+			}
+
+			for len(frame.ships) <= sid {
+				frame.ships = append(frame.ships, nil)
+			}
+
+			frame.ships[sid] = &Ship{Owner: pid, Sid: sid, X: ship_line[1], Y: ship_line[2], Halite: ship_line[3]}
+		}
+
+		for i := 0; i < player_line[2]; i++ {
+
+			dropoff_line, err := next_ints(3)
+			if err != nil {
+				return nil, err
+			}
+
+			frame.dropoffs = append(frame.dropoffs, &Dropoff{Owner: pid, Sid: dropoff_line[0], X: dropoff_line[1], Y: dropoff_line[2]})
+		}
+	}
+
+	count_line, err := next_ints(1)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < count_line[0]; i++ {
+
+		cell_line, err := next_ints(3)
+		if err != nil {
+			return nil, err
+		}
+
+		x, y := cell_line[0], cell_line[1]
+
+		if x < 0 || x >= frame.Width() || y < 0 || y >= frame.Height() {
+			return nil, fmt.Errorf("FrameFromUpdate: bad cell %d %d", x, y)
+		}
+
+		frame.halite[x][y] = cell_line[2]
+	}
+
+	return frame, nil
+}
+
+func int_line_reader(scanner *bufio.Scanner, caller string) func(n int) ([]int, error) {
+
+	// Returns a function which reads the next line, which must hold exactly n ints.
+
+	return func(n int) ([]int, error) {
+		if scanner.Scan() == false {
+			return nil, fmt.Errorf("%s: unexpected end of input", caller)
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != n {
+			return nil, fmt.Errorf("%s: wanted %d values, got %d", caller, n, len(fields))
+		}
+		var ret []int
+		for _, field := range fields {
+			val, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, val)
+		}
+		return ret, nil
+	}
+}
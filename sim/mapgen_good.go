@@ -38,6 +38,19 @@ func SizeFromSeed(seed uint32) int {
 }
 
 func MapGenOfficial(players, width, height, player_energy int, seed uint32) *Frame {
+	return map_gen(players, width, height, player_energy, seed, false)
+}
+
+func MapGenBounded(players, width, height, player_energy int, seed uint32) *Frame {
+
+	// For maps whose edges are walls. The halite is the same as the official map,
+	// which is symmetrical anyway, but factories go in the middle of their tiles,
+	// since the official spacing only makes sense when the map wraps round.
+
+	return map_gen(players, width, height, player_energy, seed, true)
+}
+
+func map_gen(players, width, height, player_energy int, seed uint32, bounded bool) *Frame {
 
 	mt19937_32.Seed(seed)
 
@@ -91,7 +104,7 @@ func MapGenOfficial(players, width, height, player_energy int, seed uint32) *Fra
 		}
 	}
 
-	place_factories(frame, players, tile_width, tile_height)
+	place_factories(frame, players, tile_width, tile_height, bounded)
 
 	return frame
 }
@@ -206,7 +219,7 @@ func make_tile(tile_width, tile_height int) [][]int {
 	return tile
 }
 
-func place_factories(frame *Frame, players, tile_width, tile_height int) {
+func place_factories(frame *Frame, players, tile_width, tile_height int, bounded bool) {
 
 	width := frame.Width()
 	height := frame.Height()
@@ -216,7 +229,7 @@ func place_factories(frame *Frame, players, tile_width, tile_height int) {
 	dx := tile_width / 2
 	dy := tile_height / 2
 
-	if tile_width >= 16 && tile_width <= 40 && tile_height >= 16 && tile_height <= 40 {
+	if tile_width >= 16 && tile_width <= 40 && tile_height >= 16 && tile_height <= 40 && bounded == false {
 		dx = int(8.0 + (float64(tile_width - 16) / 24.0) * 20.0)
 		if players > 2 {
 			dy = int(8.0 + (float64(tile_height - 16) / 24.0) * 20.0)
@@ -0,0 +1,196 @@
+package sim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A single bot command in typed form. The text protocol ("g", "m 12 n", "c 7") is
+// parsed into these, but Go code can also make them directly.
+
+type Move struct {
+	Type						string		`json:"type"`					// "g" (generate), "m" (move) or "c" (construct)
+	Sid							int			`json:"id"`						// Not used by "g"
+	Direction					string		`json:"direction,omitempty"`	// "n", "s", "e", "w" or "o", for "m" only. "" is allowed, and means no move at all.
+}
+
+func (self Move) String() string {
+	switch self.Type {
+	case "g":
+		return "g"
+	case "c":
+		return fmt.Sprintf("c %d", self.Sid)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %d %s", self.Type, self.Sid, self.Direction))
+}
+
+func MovesString(moves []Move) string {
+
+	// The text a bot would send for these moves.
+
+	var parts []string
+	for _, move := range moves {
+		parts = append(parts, move.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Why a player's moves were rejected (which gets them killed). Kind is one of the
+// constants below, for programs; Message is what we print, for humans.
+
+type MoveError struct {
+	Pid							int			`json:"pid"`
+	Index						int			`json:"index"`					// Which of the player's moves was bad, or -1 if none in particular
+	Sid							int			`json:"id"`						// The ship concerned, or -1
+	Kind						string		`json:"kind"`
+	Message						string		`json:"message"`
+}
+
+const (
+	BAD_SYNTAX					= "bad_syntax"
+	UNKNOWN_COMMAND				= "unknown_command"
+	UNKNOWN_DIRECTION			= "unknown_direction"
+	MULTIPLE_GENERATE			= "multiple_generate"
+	NO_SUCH_SHIP				= "no_such_ship"
+	NOT_OWNER					= "not_owner"
+	MULTIPLE_COMMANDS			= "multiple_commands"
+	CONSTRUCT_OVER_STRUCTURE	= "construct_over_structure"
+	OVER_BUDGET					= "over_budget"
+)
+
+func (self *MoveError) Error() string {
+	return self.Message
+}
+
+func move_error(pid, index, sid int, kind string, format string, args ...interface{}) *MoveError {
+	return &MoveError{
+		Pid: pid,
+		Index: index,
+		Sid: sid,
+		Kind: kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func ParseMoves(pid int, s string) ([]Move, *MoveError) {
+
+	// Returns the moves up to the first syntax error, if any, and the error.
+	// The moves should still be validated, since an invalid move before the bad
+	// syntax is what the bot should be told about.
+
+	var ret []Move
+
+	tokens := tokens_from_cmd_string(s)
+
+	for i := 0; i < len(tokens); i++ {
+
+		command := tokens[i]
+
+		if command == "g" {
+			ret = append(ret, Move{Type: "g"})
+			continue
+		}
+
+		if command != "m" && command != "c" {
+			return ret, move_error(pid, len(ret), -1, UNKNOWN_COMMAND, "Bot %v sent unknown command \"%s\"", pid, command)
+		}
+
+		i++
+
+		if i >= len(tokens) {			// A final command with no sid is ignored, as it always was.
+			break
+		}
+
+		sid, err := strconv.Atoi(tokens[i])
+
+		if err != nil {
+			return ret, move_error(pid, len(ret), -1, BAD_SYNTAX, "%s", err.Error())
+		}
+
+		if command == "c" {
+			ret = append(ret, Move{Type: "c", Sid: sid})
+			continue
+		}
+
+		i++
+
+		if i >= len(tokens) {			// A final move with no direction is checked, but isn't a move.
+			ret = append(ret, Move{Type: "m", Sid: sid})
+			break
+		}
+
+		ret = append(ret, Move{Type: "m", Sid: sid, Direction: tokens[i]})
+	}
+
+	return ret, nil
+}
+
+func (self *Game) validate_moves(pid int, player_moves []Move, moves map[int]string) (bool, *MoveError) {
+
+	// Checks one player's moves against the current frame, adding them to the
+	// moves map (sid --> n, s, e, w, o, c) as it goes. Returns whether they are
+	// generating, and the reason for failure, or nil if success.
+
+	gen := false
+	seen := make(map[int]bool)			// Can't just check moves, since a direction of "" is allowed
+
+	for i, move := range player_moves {
+
+		if move.Type == "g" {
+			if gen {
+				return gen, move_error(pid, i, -1, MULTIPLE_GENERATE, "Bot %v sent 2 or more generate commands", pid)
+			}
+			gen = true
+			continue
+		}
+
+		if move.Type != "m" && move.Type != "c" {
+			return gen, move_error(pid, i, -1, UNKNOWN_COMMAND, "Bot %v sent unknown command \"%s\"", pid, move.Type)
+		}
+
+		sid := move.Sid
+
+		if sid >= len(self.frame.ships) || sid < 0 || self.frame.ships[sid] == nil {
+			return gen, move_error(pid, i, sid, NO_SUCH_SHIP, "Bot %v sent command for non-existent ship %d", pid, sid)
+		}
+
+		// So the sid is a valid ship...
+
+		ship := self.frame.ships[sid]
+
+		if ship.Owner != pid {
+			return gen, move_error(pid, i, sid, NOT_OWNER, "Bot %v sent command for ship %d owned by player %d", pid, sid, ship.Owner)
+		}
+
+		// So the ship is indeed owned by the player...
+
+		if seen[sid] {
+			return gen, move_error(pid, i, sid, MULTIPLE_COMMANDS, "Bot %v sent 2 or more commands for ship %d", pid, sid)
+		}
+
+		seen[sid] = true
+
+		if move.Type == "c" {
+
+			for _, dropoff := range self.frame.dropoffs {
+				if dropoff.X == ship.X && dropoff.Y == ship.Y {
+					return gen, move_error(pid, i, sid, CONSTRUCT_OVER_STRUCTURE, "Bot %v sent construct command from ship %d over a structure", pid, sid)
+				}
+			}
+
+			moves[sid] = "c"
+			continue
+		}
+
+		direction := move.Direction
+
+		if direction != "n" && direction != "s" && direction != "e" && direction != "w" && direction != "o" && direction != "" {
+			return gen, move_error(pid, i, sid, UNKNOWN_DIRECTION, "Bot %v sent unknown direction \"%s\"", pid, direction)
+		}
+
+		moves[sid] = direction
+	}
+
+	return gen, nil
+}
@@ -0,0 +1,314 @@
+package sim
+
+import (
+	"math/rand"
+)
+
+// Obstacles are cells ships can't enter. They're stored in the halite grid as -1, so
+// they go everywhere the grid does (pregame, snapshots, replays) without any change to
+// the formats. A move into one is the same as staying still. Inspiration only counts
+// ships that could be walked to, around the obstacles.
+//
+// Maps get obstacles from a PNG, a replay, or AddObstacles().
+
+const OBSTACLE = -1
+
+func (self *Frame) IsObstacle(x, y int) bool {
+	return self.HaliteAt(x, y) == OBSTACLE
+}
+
+func (self *Frame) HasObstacles() bool {
+	for x := 0; x < self.Width(); x++ {
+		for y := 0; y < self.Height(); y++ {
+			if self.halite[x][y] == OBSTACLE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *Frame) can_enter(x, y int, bounded bool) bool {
+	if bounded && self.on_map(x, y) == false {
+		return false
+	}
+	return self.halite[mod(x, self.Width())][mod(y, self.Height())] != OBSTACLE
+}
+
+func (self *Frame) walkable_within(x, y, radius int, bounded bool) []Position {
+
+	// The cells (normalised) that can be reached from x, y in radius steps or fewer,
+	// including x, y itself.
+
+	start := Position{x, y}
+	seen := map[Position]bool{start: true}
+	ret := []Position{start}
+	edge := []Position{start}
+
+	for step := 0; step < radius && len(edge) > 0; step++ {
+
+		var next_edge []Position
+
+		for _, pos := range edge {
+			for _, d := range []string{"n", "s", "e", "w"} {
+				dx, dy := string_to_dxdy(d)
+				if self.can_enter(pos.X + dx, pos.Y + dy, bounded) == false {
+					continue
+				}
+				next := Position{mod(pos.X + dx, self.Width()), mod(pos.Y + dy, self.Height())}
+				if seen[next] == false {
+					seen[next] = true
+					ret = append(ret, next)
+					next_edge = append(next_edge, next)
+				}
+			}
+		}
+
+		edge = next_edge
+	}
+
+	return ret
+}
+
+func (self *Frame) fix_inspiration_around_obstacles(RADIUS int, SHIPS_NEEDED int, BOUNDED bool) {
+
+	// Like fix_inspiration(), but the radius is walking distance.
+
+	xy_lookup := make(map[Position]*Ship)
+
+	for _, ship := range self.ships {
+		if ship != nil {
+			xy_lookup[Position{ship.X, ship.Y}] = ship
+		}
+	}
+
+	for _, ship := range self.ships {
+
+		if ship == nil {
+			continue
+		}
+
+		hits := 0
+
+		for _, pos := range self.walkable_within(ship.X, ship.Y, RADIUS, BOUNDED) {
+			other := xy_lookup[pos]
+			if other != nil && other.Owner != ship.Owner {
+				hits++
+			}
+		}
+
+		ship.Inspired = hits >= SHIPS_NEEDED
+	}
+}
+
+// ------------------------------------------------------------------------------------------
+
+func AddObstacles(frame *Frame, percent int, seed uint32, bounded bool) {
+
+	// Turns about percent of the map into obstacles, with the same symmetry as the
+	// official mapgen, so that no player is favoured. Cells near factories are kept
+	// clear, and every other cell stays reachable from every factory.
+
+	width := frame.Width()
+	height := frame.Height()
+	players := frame.Players()
+
+	rng := rand.New(rand.NewSource(int64(seed)))
+
+	wanted := width * height * percent / 100
+	placed := 0
+
+	for attempt := 0; placed < wanted && attempt < width * height * 4; attempt++ {
+
+		x, y := rng.Intn(width), rng.Intn(height)
+
+		group := []Position{{x, y}}				// A slice, not a map, so the order is fixed
+
+		if players > 1 {
+			group = append(group, Position{width - 1 - x, y})
+		}
+
+		if players > 2 {
+			group = append(group, Position{x, height - 1 - y}, Position{width - 1 - x, height - 1 - y})
+		}
+
+		ok := true
+
+		for _, pos := range group {
+			if frame.halite[pos.X][pos.Y] == OBSTACLE {
+				ok = false
+			}
+			for _, factory := range frame.dropoffs[:players] {
+				if frame.distance(pos.X, pos.Y, factory.X, factory.Y, bounded) <= 2 {
+					ok = false
+				}
+			}
+		}
+
+		if ok == false {
+			continue
+		}
+
+		// Place them one at a time (the group can repeat a cell on odd sizes), checking
+		// each keeps the map connected. Usually the neighbours show that it does...
+
+		var done []Position
+		var old []int
+
+		for _, pos := range group {
+
+			if frame.halite[pos.X][pos.Y] == OBSTACLE {
+				continue
+			}
+
+			done = append(done, pos)
+			old = append(old, frame.halite[pos.X][pos.Y])
+			frame.halite[pos.X][pos.Y] = OBSTACLE
+
+			if frame.locally_connected(pos.X, pos.Y, bounded) == false && frame.neighbours_joined(pos.X, pos.Y, bounded) == false {
+				ok = false
+				break
+			}
+		}
+
+		if ok == false {
+			for n := len(done) - 1; n >= 0; n-- {
+				frame.halite[done[n].X][done[n].Y] = old[n]
+			}
+			continue
+		}
+
+		placed += len(done)
+	}
+}
+
+func (self *Frame) locally_connected(x, y int, bounded bool) bool {
+
+	// Whether the open neighbours of x, y (just made an obstacle) can still reach each
+	// other through the 8 cells around it. If so, nothing was cut off. If not, something
+	// may have been, and neighbours_joined() has to say.
+	//
+	// Going round the ring, each cell is next to the one before, so this is just whether
+	// the open N, E, S and W cells all lie in one unbroken run of open cells.
+
+	if self.Width() < 3 || self.Height() < 3 {		// The ring would overlap itself
+		return false
+	}
+
+	ring := []Position{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+	open := make([]bool, len(ring))
+	start := -1
+
+	for i, d := range ring {
+		open[i] = self.can_enter(x + d.X, y + d.Y, bounded)
+		if open[i] == false {
+			start = i
+		}
+	}
+
+	if start == -1 {
+		return true
+	}
+
+	runs := 0			// Runs of open cells that include an orthogonal neighbour
+	in_run, counted := false, false
+
+	for n := 1; n <= len(ring); n++ {
+
+		i := (start + n) % len(ring)
+
+		if open[i] == false {
+			in_run = false
+			continue
+		}
+
+		if in_run == false {
+			in_run, counted = true, false
+		}
+
+		if i % 2 == 0 && counted == false {			// Even entries are N, E, S, W
+			counted = true
+			runs++
+		}
+	}
+
+	return runs <= 1
+}
+
+func (self *Frame) neighbours_joined(x, y int, bounded bool) bool {
+
+	// Whether the open N, E, S and W neighbours of x, y (just made an obstacle) can
+	// still all reach each other. If they can, the map is still in one piece.
+
+	var open []Position
+
+	for _, d := range []Position{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		if self.can_enter(x + d.X, y + d.Y, bounded) {
+			open = append(open, Position{mod(x + d.X, self.Width()), mod(y + d.Y, self.Height())})
+		}
+	}
+
+	for n := 1; n < len(open); n++ {
+		if self.joined(open[0], open[n], bounded) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (self *Frame) joined(a, b Position, bounded bool) bool {
+
+	// Whether open cells a and b are connected. We search outwards from both at once,
+	// a cell each in turn. If they aren't connected, whichever search is in the smaller
+	// piece runs out first, so this rarely has to cover the whole map.
+	//
+	// Works on plain slices, cell x, y being number x * height + y.
+
+	width := self.Width()
+	height := self.Height()
+
+	start := []int{a.X * height + a.Y, b.X * height + b.Y}
+
+	if start[0] == start[1] {
+		return true
+	}
+
+	seen := make([]int8, width * height)			// 0 for unseen, else 1 + which search found it
+	queues := [][]int{{start[0]}, {start[1]}}
+	heads := []int{0, 0}
+
+	seen[start[0]] = 1
+	seen[start[1]] = 2
+
+	for {
+		for side := 0; side < 2; side++ {
+
+			if heads[side] == len(queues[side]) {
+				return false
+			}
+
+			cell := queues[side][heads[side]]
+			heads[side]++
+
+			cx, cy := cell / height, cell % height
+
+			for _, d := range []Position{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+
+				if self.can_enter(cx + d.X, cy + d.Y, bounded) == false {
+					continue
+				}
+
+				next := mod(cx + d.X, width) * height + mod(cy + d.Y, height)
+
+				if seen[next] == 0 {
+					seen[next] = int8(side + 1)
+					queues[side] = append(queues[side], next)
+				} else if seen[next] != int8(side + 1) {
+					return true
+				}
+			}
+		}
+	}
+}
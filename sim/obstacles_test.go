@@ -0,0 +1,163 @@
+package sim
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestObstacles(t *testing.T) {
+
+	// Ship 0 tries to move into an obstacle, so stays and mines. Ships 1 and 2 are
+	// within 4 of it, but only by going through the wall of obstacles...
+
+	cells := map[Position]int{{10, 5}: 400}
+
+	for y := 0; y < 32; y++ {
+		cells[Position{11, y}] = OBSTACLE
+	}
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 10, Y: 5, Halite: 100},
+		{Owner: 1, Sid: 1, X: 12, Y: 5},
+		{Owner: 1, Sid: 2, X: 12, Y: 6},
+	}
+
+	constants := NewConstants(2, 32, 32, 400, 42)
+	game := test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, cells))
+
+	// A bot learns of the obstacles from the pregame, and nothing in the update undoes that...
+
+	view, _, err := FrameFromPregameString(game.PregameString(0))
+	if err != nil || view.IsObstacle(11, 0) == false {
+		t.Fatalf("Obstacle not in the pregame")
+	}
+
+	update, _ := game.UpdateFromMoves([]string{"m 0 e", ""})
+
+	ship, _ := game.Frame().Ship(0)
+
+	if ship.X != 10 || ship.Halite != 200 {
+		t.Errorf("Got ship %+v, expected it to stay and mine 100", ship)
+	}
+
+	if ship.Inspired {
+		t.Errorf("Ship was inspired through the obstacles")
+	}
+
+	if view, err = FrameFromUpdate(view, update); err != nil || view.IsObstacle(11, 0) == false {
+		t.Errorf("Obstacle lost after the update")
+	}
+
+	if game.TotalHalite() != view.TotalHalite() || game.TotalHalite() < 0 {
+		t.Errorf("Obstacles counted as halite")
+	}
+}
+
+func TestAddObstacles(t *testing.T) {
+
+	frame := MapGenOfficial(4, 40, 40, 5000, 9)
+	AddObstacles(frame, 20, 9, false)
+
+	count := 0
+
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 40; y++ {
+			if frame.IsObstacle(x, y) {
+				count++
+				if frame.IsObstacle(39 - x, y) == false || frame.IsObstacle(x, 39 - y) == false {
+					t.Fatalf("Obstacles aren't symmetrical at %d %d", x, y)
+				}
+			}
+		}
+	}
+
+	if count < 40 * 40 / 10 {
+		t.Errorf("Only %d obstacles", count)
+	}
+
+	for pid := 0; pid < 4; pid++ {
+		f := frame.Factory(pid)
+		if frame.IsObstacle(f.X, f.Y) || frame.IsObstacle(f.X + 1, f.Y) {
+			t.Errorf("Obstacle next to factory %d", pid)
+		}
+	}
+
+	// Flood from factory 0; every open cell should be reached...
+
+	f := frame.Factory(0)
+	seen := map[Position]bool{{f.X, f.Y}: true}
+	queue := []Position{{f.X, f.Y}}
+
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		for _, step := range []Position{{0, -1}, {0, 1}, {1, 0}, {-1, 0}} {
+			next := Position{(pos.X + step.X + 40) % 40, (pos.Y + step.Y + 40) % 40}
+			if seen[next] == false && frame.IsObstacle(next.X, next.Y) == false {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	if len(seen) != 40 * 40 - count {
+		t.Errorf("Map isn't connected: reached %d of %d open cells", len(seen), 40 * 40 - count)
+	}
+
+	// And they survive a snapshot...
+
+	again, err := FrameFromSnapshot(frame.Snapshot())
+	if err != nil || reflect.DeepEqual(again.Halite(), frame.Halite()) == false {
+		t.Errorf("Snapshot lost the obstacles")
+	}
+}
+
+func TestObstaclesFromPNG(t *testing.T) {
+
+	// Old maps must mean what they always did: any colour is a factory, including
+	// ones with red and green equal (yellow here). Only transparency is new.
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.NRGBA{100, 100, 100, 255})
+		}
+	}
+
+	img.Set(0, 1, color.NRGBA{255, 0, 0, 255})
+	img.Set(3, 2, color.NRGBA{200, 200, 0, 255})
+	img.Set(2, 2, color.NRGBA{0, 0, 0, 0})
+
+	filename := filepath.Join(t.TempDir(), "map.png")
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	frame := FrameFromPNG(filename)
+
+	if frame.Players() != 2 {
+		t.Fatalf("Got %d factories, expected 2", frame.Players())
+	}
+
+	if f := frame.Factory(1); f.X != 3 || f.Y != 2 {
+		t.Errorf("Factory 1 at %d %d, expected 3 2", f.X, f.Y)
+	}
+
+	if frame.IsObstacle(2, 2) == false || frame.IsObstacle(1, 1) {
+		t.Errorf("Obstacles in the wrong place")
+	}
+
+	if frame.HaliteAt(1, 1) != 100 * 257 / 65 {
+		t.Errorf("Got halite %d, expected %d", frame.HaliteAt(1, 1), 100 * 257 / 65)
+	}
+}
@@ -0,0 +1,149 @@
+package sim
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+func ReplayFromFile(infile string) (*Replay, error) {
+
+	// Unlike FrameFromFile(), this reads the whole thing, including every frame.
+
+	f, err := os.Open(infile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	raw, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err = decompress_if_needed(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := new(Replay)
+
+	err = json.Unmarshal(raw, replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return replay, nil
+}
+
+func (self *Replay) Frames() []*Frame {
+
+	// Rebuilds the state at the start of each turn, i.e. the state each
+	// ReplayFrame's moves were applied to. Item 0 is the initial map.
+	//
+	// Replay frame n holds the entities at the start of turn n, but the
+	// energies, cell changes and events that resulted from turn n, so we
+	// need both frame n - 1 and frame n to build state n.
+
+	players := len(self.Players)
+	width := self.ProductionMap.Width
+	height := self.ProductionMap.Height
+
+	frame := new(Frame)
+
+	for pid := 0; pid < players; pid++ {
+		frame.budgets = append(frame.budgets, self.Constants.INITIAL_ENERGY)
+		frame.deposited = append(frame.deposited, 0)
+		frame.last_alive = append(frame.last_alive, -1)
+	}
+
+	frame.halite = make_2d_int_array(width, height)
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			frame.halite[x][y] = self.ProductionMap.Grid[y][x].Energy		// y/x inversion
+		}
+	}
+
+	for pid := 0; pid < players; pid++ {
+
+		x := self.Players[pid].FactoryLocation.X
+		y := self.Players[pid].FactoryLocation.Y
+
+		frame.dropoffs = append(frame.dropoffs, &Dropoff{
+			Factory: true,
+			Owner: pid,
+			Sid: -1,
+			X: x,
+			Y: y,
+		})
+
+		frame.halite[x][y] = 0
+	}
+
+	var ret []*Frame
+
+	for n, rf := range self.FullFrames {
+
+		if n > 0 {
+
+			prev := self.FullFrames[n - 1]
+
+			frame = frame.Copy()
+			frame.turn = n
+			frame.ships = nil
+
+			for pid := 0; pid < players; pid++ {
+				frame.budgets[pid] = prev.Energy[pid]
+				frame.deposited[pid] = prev.Deposited[pid]
+			}
+
+			for _, cell := range prev.Cells {
+				frame.halite[cell.X][cell.Y] = cell.Production
+			}
+
+			for _, event := range prev.Events {
+
+				if event.Type != "construct" {
+					continue
+				}
+
+				frame.dropoffs = append(frame.dropoffs, &Dropoff{
+					Factory: false,
+					Owner: event.Owner,
+					Sid: len(frame.dropoffs) - players,			// Same rule as the engine uses
+					X: event.Location.X,
+					Y: event.Location.Y,
+				})
+			}
+		}
+
+		// The ships are never copied across; each replay frame has all of them.
+
+		for pid, ships := range rf.Entities {
+			for sid, ship := range ships {
+
+				for len(frame.ships) <= sid {
+					frame.ships = append(frame.ships, nil)
+				}
+
+				frame.ships[sid] = &Ship{
+					Owner: pid,
+					Sid: sid,
+					X: ship.X,
+					Y: ship.Y,
+					Halite: ship.Halite,
+					Inspired: ship.Inspired,
+				}
+			}
+		}
+
+		ret = append(ret, frame)
+	}
+
+	return ret
+}
+
+func BotUpdateString(old, current *Frame) string {
+	return make_bot_update_string(old, current)
+}
@@ -0,0 +1,40 @@
+package sim
+
+// Halite regrowth, a variant where cells below some percentage (Constants.REGROWTH_TARGET)
+// of their initial halite regain 1 / Constants.REGROWTH_RATIO of the shortfall each turn.
+// It's a setting rather than a ruleset, so NewGame() wraps whatever rules are in use
+// with it, when the ratio is set.
+
+type RegrowthRules struct {
+	Ruleset
+}
+
+func (self RegrowthRules) EndOfTurn(game *Game, frame *Frame) []*ReplayEvent {
+	events := self.Ruleset.EndOfTurn(game, frame)
+	frame.regrow(game.initial_halite, game.Constants.REGROWTH_RATIO, game.Constants.REGROWTH_TARGET)
+	return events
+}
+
+func (self *Frame) regrow(initial [][]int, ratio, target_percent int) {
+
+	// Cells below their target regain a share of the difference (at least 1).
+	// Structures' cells don't, as whatever's there would be delivered for free.
+
+	structures := make(map[Position]bool)
+	for _, dropoff := range self.dropoffs {
+		structures[Position{dropoff.X, dropoff.Y}] = true
+	}
+
+	for x := 0; x < self.Width(); x++ {
+		for y := 0; y < self.Height(); y++ {
+
+			target := initial[x][y] * target_percent / 100
+
+			if self.halite[x][y] >= target || self.halite[x][y] == OBSTACLE || structures[Position{x, y}] {
+				continue
+			}
+
+			self.halite[x][y] += (target - self.halite[x][y] + ratio - 1) / ratio
+		}
+	}
+}
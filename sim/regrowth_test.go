@@ -0,0 +1,66 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestRegrowth(t *testing.T) {
+
+	// Ship 0 mines its cell, which then regrows a tenth of what was taken (the target
+	// being 100% of the original). Ship 1 builds a dropoff, whose cell mustn't regrow.
+
+	constants := NewConstants(2, 32, 32, 400, 42)
+	constants.REGROWTH_RATIO = 10
+	constants.REGROWTH_TARGET = 100
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 5, Y: 5},
+		{Owner: 0, Sid: 1, X: 8, Y: 8},
+	}
+
+	game := test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, nil))
+
+	initial := game.HaliteAt(5, 5)
+
+	if initial < 100 || game.HaliteAt(8, 8) == 0 {
+		t.Fatalf("Map has changed; pick other cells")
+	}
+
+	mined := (initial + 3) / 4
+	expected := initial - mined + (mined + 9) / 10
+
+	before := game.Frame()
+	update, rf := game.UpdateFromMoves([]string{"c 1", ""})
+
+	if game.HaliteAt(5, 5) != expected {
+		t.Errorf("Cell regrew to %d, expected %d", game.HaliteAt(5, 5), expected)
+	}
+
+	if game.HaliteAt(8, 8) != 0 {
+		t.Errorf("Dropoff cell regrew")
+	}
+
+	for _, cell := range rf.Cells {
+		if cell.X == 5 && cell.Y == 5 && cell.Production != expected {
+			t.Errorf("Replay has the cell at %d, expected %d", cell.Production, expected)
+		}
+	}
+
+	if view, _ := FrameFromUpdate(before, update); view.HaliteAt(5, 5) != expected {
+		t.Errorf("Update didn't include the regrowth")
+	}
+
+	// Regrowth isn't part of any ruleset, so it goes with the others too...
+
+	constants = NewConstants(2, 32, 32, 400, 42)
+	constants.RULES = "capture"
+	constants.REGROWTH_RATIO = 10
+	constants.REGROWTH_TARGET = 100
+
+	game = test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, nil))
+	game.UpdateFromMoves([]string{"", ""})
+
+	if game.HaliteAt(5, 5) != expected {
+		t.Errorf("With capture rules, cell regrew to %d, expected %d", game.HaliteAt(5, 5), expected)
+	}
+}
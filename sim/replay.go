@@ -3,7 +3,10 @@ package sim
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 const PRETTY_PRINT = false
@@ -44,7 +47,7 @@ func NewReplay(names []string, game *Game, turns int, seed uint32) *Replay {
 	return self
 }
 
-func (self *Replay) Dump(filename string) {
+func (self *Replay) Dump(filename string, compress bool) {
 
 	outfile, err := os.Create(filename)
 	if err != nil {
@@ -53,7 +56,19 @@ func (self *Replay) Dump(filename string) {
 	}
 	defer outfile.Close()
 
-	enc := json.NewEncoder(outfile)
+	var w io.Writer = outfile
+	var zw *zstd.Encoder
+
+	if compress {									// Like Official, the .hlt is a zstd stream of the JSON
+		zw, err = zstd.NewWriter(outfile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+		w = zw
+	}
+
+	enc := json.NewEncoder(w)
 
 	if PRETTY_PRINT {
 		enc.SetIndent("", "\t")			// Horrifically wasteful
@@ -63,8 +78,19 @@ func (self *Replay) Dump(filename string) {
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
+		if zw != nil {
+			zw.Close()							// Still needed, to release the encoder
+		}
 		return
 	}
+
+	if zw != nil {
+		err = zw.Close()						// This is what flushes the end of the stream
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return
+		}
+	}
 }
 
 type ReplayPlayer struct {												// This is created at start and not updated
@@ -189,3 +215,18 @@ func ReplayMapFromFrame(frame *Frame) *ReplayMap {
 func (d Dropoff) MarshalJSON() ([]byte, error) {		// Strictly for replay halite_per_dropoff stat.
 	return []byte(fmt.Sprintf(`[{"x":%d,"y":%d},%d]`, d.X, d.Y, d.Gathered)), nil
 }
+
+func (d *Dropoff) UnmarshalJSON(b []byte) error {		// The reverse of the above, for loading replays.
+
+	var pos Position
+	raw := []interface{}{&pos, &d.Gathered}
+
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	d.X = pos.X
+	d.Y = pos.Y
+	return nil
+}
@@ -0,0 +1,226 @@
+package sim
+
+import (
+	"sort"
+)
+
+// The rules of the game, split into the phases that resolve() goes through, so that
+// variants can replace some of them. Each method gets the game as it was at the start
+// of the turn, and (where relevant) the new frame being made, which it may change.
+//
+// The ruleset is named by Constants.RULES, so bots and replays know which was used;
+// "" means official.
+
+type Ruleset interface {
+
+	// Adjusts the constants (before bots are sent them) for anything the rules imply.
+	Setup(c *Constants)
+
+	// What a ship pays to move off a cell with the given halite.
+	MoveCost(game *Game, ship *Ship, cell_halite int) int
+
+	// What a player pays to turn the ship into a dropoff (before the ship's cargo
+	// and the cell's halite are added back).
+	ConstructionCost(game *Game, ship *Ship) int
+
+	// Given where ships ended up, and where players are spawning, destroys ships
+	// that collide. Returns the places there were collisions, and the events.
+	Collide(game *Game, frame *Frame, ship_positions map[Position][]*Ship, spawn_points map[Position]bool) (map[Position]bool, []*ReplayEvent)
+
+	// For a ship that stayed still: how much is taken from the cell, and how much
+	// the ship gains.
+	Mine(game *Game, ship *Ship, cell_halite int) (int, int)
+
+	// Anything else that happens at the end of the turn, before inspiration.
+	EndOfTurn(game *Game, frame *Frame) []*ReplayEvent
+
+	// Sets .Inspired on every ship, for next turn.
+	Inspire(game *Game, frame *Frame)
+}
+
+var rulesets = map[string]Ruleset{
+	"official":		OfficialRules{},
+	"capture":		CaptureRules{},
+}
+
+func RulesetNames() []string {
+	var ret []string
+	for name := range rulesets {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func RulesetByName(name string) (Ruleset, bool) {
+	if name == "" {
+		name = "official"
+	}
+	rules, ok := rulesets[name]
+	return rules, ok
+}
+
+// ------------------------------------------------------------------------------------------
+
+type OfficialRules struct {}
+
+func (self OfficialRules) Setup(c *Constants) {}
+
+func (self OfficialRules) MoveCost(game *Game, ship *Ship, cell_halite int) int {
+
+	mcr := game.Constants.MOVE_COST_RATIO
+	if ship.Inspired { mcr = game.Constants.INSPIRED_MOVE_COST_RATIO }	// See note far below on .Inspiration
+
+	return cell_halite / mcr
+}
+
+func (self OfficialRules) ConstructionCost(game *Game, ship *Ship) int {
+	return game.Constants.DROPOFF_COST
+}
+
+func (self OfficialRules) Collide(game *Game, frame *Frame, ship_positions map[Position][]*Ship, spawn_points map[Position]bool) (map[Position]bool, []*ReplayEvent) {
+
+	collision_points := make(map[Position]bool)
+	events := make([]*ReplayEvent, 0)
+
+	for point, ships_here := range ship_positions {
+
+		x, y := point.X, point.Y
+
+		if len(ships_here) == 1 && spawn_points[Position{x, y}] == false {
+			continue
+		}
+
+		// Collision...
+
+		collision_points[Position{x, y}] = true
+
+		var wreckedsids []int
+
+		for _, ship := range ships_here {
+			frame.ships[ship.Sid] = nil
+			frame.halite[x][y] += ship.Halite			// Dump the halite on the ground.
+			wreckedsids = append(wreckedsids, ship.Sid)
+		}
+
+		events = append(events, &ReplayEvent{
+			Location: &Position{x, y},
+			WreckedSids: wreckedsids,
+			Type: "shipwreck",
+		})
+	}
+
+	return collision_points, events
+}
+
+func (self OfficialRules) Mine(game *Game, ship *Ship, cell_halite int) (int, int) {
+
+	c := game.Constants
+
+	exrat := c.EXTRACT_RATIO
+	if ship.Inspired { exrat = c.INSPIRED_EXTRACT_RATIO }		// See note below on .Inspiration
+
+	amount_to_mine := (cell_halite + exrat - 1) / exrat
+
+	if amount_to_mine + ship.Halite >= c.MAX_ENERGY {
+		amount_to_mine = c.MAX_ENERGY - ship.Halite
+	}
+
+	gained := amount_to_mine
+
+	// Inspired bonus... (doesn't remove halite from ground)
+
+	if ship.Inspired {				// See note below on .Inspiration
+
+		ibm := int(c.INSPIRED_BONUS_MULTIPLIER)		// May be a float in replays but we'll only accept ints
+
+		inspired_bonus := amount_to_mine * ibm
+
+		if inspired_bonus + ship.Halite + gained >= c.MAX_ENERGY {
+			inspired_bonus = c.MAX_ENERGY - ship.Halite - gained
+		}
+
+		gained += inspired_bonus
+	}
+
+	return amount_to_mine, gained
+}
+
+func (self OfficialRules) EndOfTurn(game *Game, frame *Frame) []*ReplayEvent {
+	return nil
+}
+
+func (self OfficialRules) Inspire(game *Game, frame *Frame) {
+
+	c := game.Constants
+
+	if game.obstacles {
+		frame.fix_inspiration_around_obstacles(c.INSPIRATION_RADIUS, c.INSPIRATION_SHIP_COUNT, c.BOUNDED)
+	} else {
+		frame.fix_inspiration(c.INSPIRATION_RADIUS, c.INSPIRATION_SHIP_COUNT, c.BOUNDED)
+	}
+}
+
+// ------------------------------------------------------------------------------------------
+// Capture, which was in the official code but never used. At the end of each turn, a ship
+// changes sides if another player has at least SHIPS_ABOVE_FOR_CAPTURE more ships within
+// CAPTURE_RADIUS of it than its owner does (counting itself), and more there than anyone
+// else. All captures are decided before any happen.
+
+type CaptureRules struct {
+	OfficialRules
+}
+
+func (self CaptureRules) Setup(c *Constants) {
+	c.CAPTURE_ENABLED = true
+}
+
+func (self CaptureRules) EndOfTurn(game *Game, frame *Frame) []*ReplayEvent {
+
+	events := self.OfficialRules.EndOfTurn(game, frame)
+
+	c := game.Constants
+
+	captures := make(map[int]int)			// sid --> new owner
+
+	for _, ship := range frame.ships {
+
+		if ship == nil {
+			continue
+		}
+
+		counts := make([]int, frame.Players())
+
+		for _, other := range frame.ships {
+			if other == nil {
+				continue
+			}
+			if frame.distance(ship.X, ship.Y, other.X, other.Y, c.BOUNDED) <= c.CAPTURE_RADIUS {
+				counts[other.Owner]++
+			}
+		}
+
+		best, tied := -1, false
+
+		for pid, count := range counts {
+			if pid == ship.Owner {
+				continue
+			}
+			if best == -1 || count > counts[best] {
+				best, tied = pid, false
+			} else if count == counts[best] {
+				tied = true
+			}
+		}
+
+		if best != -1 && tied == false && counts[best] - counts[ship.Owner] >= c.SHIPS_ABOVE_FOR_CAPTURE {
+			captures[ship.Sid] = best
+		}
+	}
+
+	for sid, pid := range captures {
+		frame.ships[sid].Owner = pid
+	}
+
+	return events
+}
@@ -0,0 +1,46 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestCaptureRules(t *testing.T) {
+
+	// Player 1 has 4 ships within 3 of player 0's lone ship...
+
+	ships := []Ship{
+		{Owner: 0, Sid: 0, X: 10, Y: 12},
+		{Owner: 1, Sid: 1, X: 10, Y: 10},
+		{Owner: 1, Sid: 2, X: 10, Y: 11},
+		{Owner: 1, Sid: 3, X: 11, Y: 10},
+		{Owner: 1, Sid: 4, X: 9, Y: 10},
+	}
+
+	constants := NewConstants(2, 32, 32, 400, 42)
+	constants.RULES = "capture"
+
+	game := test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, nil))
+
+	if constants.CAPTURE_ENABLED == false {
+		t.Errorf("CAPTURE_ENABLED not set")
+	}
+
+	game.UpdateFromMoves([]string{"", ""})
+
+	for _, ship := range game.Ships() {
+		if ship.Owner != 1 {
+			t.Errorf("Ship %d belongs to %d, expected 1", ship.Sid, ship.Owner)
+		}
+	}
+
+	// But official rules don't do that...
+
+	constants = NewConstants(2, 32, 32, 400, 42)
+
+	game = test_game(constants, test_frame(t, MapGenOfficial(2, 32, 32, constants.INITIAL_ENERGY, 42), ships, nil))
+	game.UpdateFromMoves([]string{"", ""})
+
+	if len(game.ShipsOf(0)) != 1 {
+		t.Errorf("Official rules captured a ship")
+	}
+}
@@ -1,6 +1,7 @@
 package sim
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,6 +17,10 @@ type Frame struct {
 	dropoffs					[]*Dropoff		// The first <player_count> items are always the factories. Index is arbitrary otherwise.
 }
 
+func (self *Frame) Turn() int {
+	return self.turn
+}
+
 func (self *Frame) Width() int {
 	return len(self.halite)
 }
@@ -32,7 +37,9 @@ func (self *Frame) TotalHalite() int {
 	count := 0
 	for x := 0; x < self.Width(); x++ {
 		for y := 0; y < self.Height(); y++ {
-			count += self.halite[x][y]
+			if self.halite[x][y] != OBSTACLE {
+				count += self.halite[x][y]
+			}
 		}
 	}
 	return count
@@ -123,7 +130,7 @@ func (self *Frame) Copy() *Frame {
 	return new_frame
 }
 
-func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int) {
+func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int, BOUNDED bool) {
 
 	width := len(self.halite)
 	height := len(self.halite[0])
@@ -139,6 +146,13 @@ func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int) {
 		xy_lookup[Position{ship.X, ship.Y}] = ship
 	}
 
+	lookup := func(x, y int) *Ship {
+		if BOUNDED && (x < 0 || x >= width || y < 0 || y >= height) {
+			return nil
+		}
+		return xy_lookup[Position{mod(x, width), mod(y, height)}]
+	}
+
 	for _, ship := range self.ships {
 
 		if ship == nil {
@@ -154,10 +168,7 @@ func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int) {
 
 			for x := startx; x <= endx; x++ {
 
-				other_x := mod(ship.X + x, width)
-				other_y := mod(ship.Y + y, height)
-
-				other := xy_lookup[Position{other_x, other_y}]
+				other := lookup(ship.X + x, ship.Y + y)
 
 				if other != nil {
 					if other.Owner != ship.Owner {
@@ -167,9 +178,7 @@ func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int) {
 
 				if y != 0 {
 
-					other_y = mod(ship.Y - y, height)
-
-					other := xy_lookup[Position{other_x, other_y}]
+					other := lookup(ship.X + x, ship.Y - y)
 
 					if other != nil {
 						if other.Owner != ship.Owner {
@@ -192,7 +201,13 @@ func (self *Frame) fix_inspiration(RADIUS int, SHIPS_NEEDED int) {
 
 type Game struct {
 	Constants					*Constants
+	Rules						Ruleset		// Chosen by Constants.RULES
 	frame						*Frame
+
+	initial_halite				[][]int		// For regrowth
+	obstacles					bool		// Whether the map has any, which never changes during a game
+	fog_known					[][][]int	// pid --> halite as last seen, with fog of war
+	player_updates				[]string	// pid --> update string, with fog of war
 }
 
 func NewGame(constants *Constants) *Game {
@@ -202,11 +217,38 @@ func NewGame(constants *Constants) *Game {
 	self.Constants = constants
 	self.frame = nil				// To be set by caller.
 
+	// An unknown ruleset (e.g. from some other engine's replay) gets the official
+	// rules; main() checks the name before we get here.
+
+	rules, ok := RulesetByName(constants.RULES)
+	if ok == false {
+		rules = OfficialRules{}
+	}
+
+	// Regrowth isn't a ruleset of its own, but a setting that goes with any of them...
+
+	if constants.REGROWTH_RATIO > 0 {
+		rules = RegrowthRules{rules}
+	}
+
+	self.Rules = rules
+	self.Rules.Setup(constants)
+
 	return self
 }
 
 func (self *Game) UseFrame(f *Frame) {
+
+	// Note that the frame is taken as the initial one, for regrowth. When starting
+	// from a snapshot, cells regrow towards the snapshot's halite.
+
 	self.frame = f
+	self.obstacles = f.HasObstacles()
+
+	self.initial_halite = make_2d_int_array(f.Width(), f.Height())
+	for x := 0; x < f.Width(); x++ {
+		copy(self.initial_halite[x], f.halite[x])
+	}
 }
 
 func (self *Game) BotInitString() string {
@@ -241,6 +283,18 @@ func (self *Game) BotInitString() string {
 	return strings.Join(lines, "\n")		// There is no final newline returned.
 }
 
+func (self *Game) PregameString(pid int) string {
+
+	// Everything a bot is sent before the game: the constants JSON (with no
+	// spaces, as some starter kits expect), player count and pid, then the above.
+	// Again, no final newline.
+
+	json_blob_bytes, _ := json.Marshal(self.Constants)
+	json_blob := strings.Replace(string(json_blob_bytes), " ", "", -1)
+
+	return fmt.Sprintf("%s\n%d %d\n%s", json_blob, self.frame.Players(), pid, self.BotInitString())
+}
+
 func (self *Game) GetRank(pid int) int {
 
 	money := self.frame.budgets[pid]
@@ -293,6 +347,19 @@ func (self *Game) TotalHalite() int {
 	return self.frame.TotalHalite()
 }
 
+func (self *Game) Snapshot() string {
+	return self.frame.Snapshot()
+}
+
+func (self *Game) CurrentUpdateString() string {
+
+	// The update for the current state without simulating anything, e.g. when
+	// starting from a snapshot. No cells have changed since the bots got the map.
+
+	self.make_player_updates(self.frame, self.frame)
+	return make_bot_update_string(self.frame, self.frame)
+}
+
 type Dropoff struct {
 	Factory						bool
 	Owner						int
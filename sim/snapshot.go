@@ -0,0 +1,222 @@
+package sim
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A snapshot is a complete Frame as a short string, for passing positions around.
+// It's a list of varints, deflated, in URL-safe base64, after a version prefix:
+//
+//		turn, players, width, height
+//		per player: budget, deposited, last_alive
+//		halite, column by column (x outer, y inner, like our arrays)
+//		len(ships), live ship count, then per live ship: sid, owner, x, y, halite, inspired
+//		dropoff count, then per dropoff: factory, owner, sid, x, y, gathered
+//
+// len(ships) is stored so that the next ship gets the right sid.
+
+const SNAPSHOT_PREFIX = "dub1:"
+
+// Limits for reading, so a mangled snapshot can't make us allocate without end. The
+// counts in a snapshot are also checked against how much input is left, since every
+// item they count takes at least one byte per varint.
+
+const MAX_SNAPSHOT_BYTES = 16 * 1024 * 1024		// After inflating
+const MAX_SNAPSHOT_SIDS = 1024 * 1024			// i.e. ships ever built, and also the latest turn
+const MAX_SNAPSHOT_HALITE = 1 << 40				// For any one count of halite, so sums can't overflow
+
+func (self *Frame) Snapshot() string {
+
+	var vals []int
+
+	vals = append(vals, self.turn, self.Players(), self.Width(), self.Height())
+
+	for pid := 0; pid < self.Players(); pid++ {
+		vals = append(vals, self.budgets[pid], self.deposited[pid], self.last_alive[pid])
+	}
+
+	for x := 0; x < self.Width(); x++ {
+		vals = append(vals, self.halite[x]...)
+	}
+
+	var live []*Ship
+
+	for _, ship := range self.ships {
+		if ship != nil {
+			live = append(live, ship)
+		}
+	}
+
+	vals = append(vals, len(self.ships), len(live))
+
+	for _, ship := range live {
+		vals = append(vals, ship.Sid, ship.Owner, ship.X, ship.Y, ship.Halite, bool_to_int(ship.Inspired))
+	}
+
+	vals = append(vals, len(self.dropoffs))
+
+	for _, dropoff := range self.dropoffs {
+		vals = append(vals, bool_to_int(dropoff.Factory), dropoff.Owner, dropoff.Sid, dropoff.X, dropoff.Y, dropoff.Gathered)
+	}
+
+	var raw bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	for _, val := range vals {
+		n := binary.PutVarint(buf, int64(val))
+		raw.Write(buf[:n])
+	}
+
+	var compressed bytes.Buffer
+
+	w, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	w.Write(raw.Bytes())
+	w.Close()
+
+	return SNAPSHOT_PREFIX + base64.RawURLEncoding.EncodeToString(compressed.Bytes())
+}
+
+func FrameFromSnapshot(s string) (frame *Frame, err error) {
+
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, SNAPSHOT_PREFIX) == false {
+		return nil, fmt.Errorf("FrameFromSnapshot: not a snapshot (expected prefix %q)", SNAPSHOT_PREFIX)
+	}
+
+	compressed, err := base64.RawURLEncoding.DecodeString(s[len(SNAPSHOT_PREFIX):])
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), MAX_SNAPSHOT_BYTES + 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > MAX_SNAPSHOT_BYTES {
+		return nil, fmt.Errorf("FrameFromSnapshot: too big")
+	}
+
+	r := bytes.NewReader(raw)
+
+	// Rather than check every read, a short or mangled snapshot panics and we recover here.
+
+	defer func() {
+		if p := recover(); p != nil {
+			frame, err = nil, fmt.Errorf("FrameFromSnapshot: %v", p)
+		}
+	}()
+
+	next := func() int {
+		val, err := binary.ReadVarint(r)
+		if err != nil {
+			panic("truncated snapshot")
+		}
+		return int(val)
+	}
+
+	next_count := func(min, max int, what string) int {
+		val := next()
+		if val < min || val > max {
+			panic("bad " + what)
+		}
+		return val
+	}
+
+	frame = new(Frame)
+
+	frame.turn = next_count(0, MAX_SNAPSHOT_SIDS, "turn")
+	players := next_count(1, r.Len() / 3, "player count")
+	width := next_count(1, r.Len(), "width")
+	height := next_count(1, r.Len(), "height")
+
+	if width * height > r.Len() {
+		panic("bad dimensions")
+	}
+
+	for pid := 0; pid < players; pid++ {
+		frame.budgets = append(frame.budgets, next_count(0, MAX_SNAPSHOT_HALITE, "budget"))
+		frame.deposited = append(frame.deposited, next_count(0, MAX_SNAPSHOT_HALITE, "deposited"))
+		frame.last_alive = append(frame.last_alive, next_count(-1, frame.turn, "last_alive"))
+	}
+
+	frame.halite = make_2d_int_array(width, height)
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			frame.halite[x][y] = next_count(OBSTACLE, MAX_SNAPSHOT_HALITE, "halite")
+		}
+	}
+
+	frame.ships = make([]*Ship, next_count(0, MAX_SNAPSHOT_SIDS, "ship count"))
+	live_count := next_count(0, r.Len() / 6, "live ship count")
+
+	if live_count > len(frame.ships) || live_count > width * height {
+		panic("bad live ship count")
+	}
+
+	for i := 0; i < live_count; i++ {
+		ship := &Ship{Sid: next(), Owner: next(), X: next(), Y: next(), Halite: next(), Inspired: next() != 0}
+		if ship.Sid < 0 || ship.Sid >= len(frame.ships) || ship.Owner < 0 || ship.Owner >= players || ship.X < 0 || ship.X >= width || ship.Y < 0 || ship.Y >= height {
+			panic("bad ship")
+		}
+		if ship.Halite < 0 || ship.Halite > MAX_SNAPSHOT_HALITE || frame.halite[ship.X][ship.Y] == OBSTACLE {
+			panic("bad ship")
+		}
+		if frame.ships[ship.Sid] != nil {
+			panic("repeated ship sid")
+		}
+		frame.ships[ship.Sid] = ship
+	}
+
+	dropoff_count := next_count(0, r.Len() / 6, "dropoff count")
+
+	if dropoff_count > width * height {
+		panic("bad dropoff count")
+	}
+
+	if dropoff_count < players {
+		panic("missing factories")
+	}
+
+	// Everything expects the factories first, in pid order (e.g. dropoffs[pid] is
+	// player pid's factory), and no 2 structures in one place...
+
+	structures := make(map[Position]bool)
+	sids := make(map[int]bool)
+
+	for i := 0; i < dropoff_count; i++ {
+		dropoff := &Dropoff{Factory: next() != 0, Owner: next(), Sid: next(), X: next(), Y: next(), Gathered: next()}
+		if dropoff.Owner < 0 || dropoff.Owner >= players || dropoff.X < 0 || dropoff.X >= width || dropoff.Y < 0 || dropoff.Y >= height {
+			panic("bad dropoff")
+		}
+		if i < players && (dropoff.Factory == false || dropoff.Owner != i || dropoff.Sid != -1) {
+			panic("bad factory")
+		}
+		if i >= players && (dropoff.Factory || dropoff.Sid < 0 || sids[dropoff.Sid]) {
+			panic("bad dropoff")
+		}
+		if dropoff.Gathered < 0 || structures[Position{dropoff.X, dropoff.Y}] || frame.halite[dropoff.X][dropoff.Y] == OBSTACLE {
+			panic("bad dropoff")
+		}
+		structures[Position{dropoff.X, dropoff.Y}] = true
+		sids[dropoff.Sid] = true
+		frame.dropoffs = append(frame.dropoffs, dropoff)
+	}
+
+	return frame, nil
+}
+
+func bool_to_int(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package sim
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+func snapshot_of_vals(vals ...int) string {
+
+	// A snapshot made by hand, which needn't make any sense.
+
+	var raw bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	for _, val := range vals {
+		n := binary.PutVarint(buf, int64(val))
+		raw.Write(buf[:n])
+	}
+
+	var compressed bytes.Buffer
+
+	w, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	w.Write(raw.Bytes())
+	w.Close()
+
+	return SNAPSHOT_PREFIX + base64.RawURLEncoding.EncodeToString(compressed.Bytes())
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+
+	// Every state of a real game, with its deaths, wrecks and dropoffs, must pass the
+	// checks in FrameFromSnapshot() and come back the same.
+
+	game := new_test_game(4)
+	rng := rand.New(rand.NewSource(4))
+
+	for turn := 0; turn < 150; turn++ {
+
+		frame, err := FrameFromSnapshot(game.Snapshot())
+		if err != nil {
+			t.Fatalf("Turn %d: %v", game.Turn(), err)
+		}
+
+		if frame.Snapshot() != game.Snapshot() {
+			t.Fatalf("Turn %d: snapshot changed after a round trip", game.Turn())
+		}
+
+		var strs []string
+		for _, player_moves := range random_moves(game, rng) {
+			strs = append(strs, MovesString(player_moves))
+		}
+
+		game.UpdateFromMoves(strs)
+	}
+}
+
+func TestSnapshotLimits(t *testing.T) {
+
+	// Each of these asks for a huge allocation, and must be refused rather than tried.
+
+	header := []int{0, 1, 2, 1, 5000, 0, -1, 0, 0}		// 1 player, 2x1 map with no halite
+
+	bad := map[string][]int{
+		"players":		{0, 1 << 40, 2, 1},
+		"width":		{0, 1, 1 << 40, 1},
+		"area":			{0, 1, 1 << 20, 1 << 20},
+		"sids":			append(append([]int{}, header...), 1 << 40, 0),
+		"live ships":	append(append([]int{}, header...), 1, 1 << 40),
+		"dropoffs":		append(append([]int{}, header...), 0, 0, 1 << 40),
+	}
+
+	for name, vals := range bad {
+		if _, err := FrameFromSnapshot(snapshot_of_vals(vals...)); err == nil {
+			t.Errorf("Bad %s: no error", name)
+		}
+	}
+
+	// And the same header is fine when the counts are sensible...
+
+	good := append(append([]int{}, header...), 0, 0, 1, 1, 0, -1, 0, 0, 0)
+
+	if _, err := FrameFromSnapshot(snapshot_of_vals(good...)); err != nil {
+		t.Errorf("Good snapshot: %v", err)
+	}
+}
+
+func TestSnapshotValidation(t *testing.T) {
+
+	// A hand-edited snapshot mustn't give a Frame the rest of the code can't cope with.
+	// The good one is 2 players on a 2x1 map, each with a factory, and player 0's ship
+	// sitting on player 1's.
+
+	good := [][]int{
+		{0, 2, 2, 1},										// turn, players, width, height
+		{5000, 0, -1, 5000, 0, -1},							// budget, deposited, last_alive
+		{0, 0},												// halite
+		{1, 1, 0, 0, 1, 0, 0, 0},							// ships
+		{2, 1, 0, -1, 0, 0, 0, 1, 1, -1, 1, 0, 0},			// dropoffs
+	}
+
+	bad := map[string][][]int{
+		"negative turn":		{{-1, 2, 2, 1}, nil, nil, nil, nil},
+		"negative budget":		{nil, {-1, 0, -1, 5000, 0, -1}, nil, nil, nil},
+		"death after turn":		{nil, {5000, 0, 3, 5000, 0, -1}, nil, nil, nil},
+		"negative halite":		{nil, nil, {0, -5}, nil, nil},
+		"ship on obstacle":		{nil, nil, {0, OBSTACLE}, nil, nil},
+		"ship halite":			{nil, nil, nil, {1, 1, 0, 0, 1, 0, -1, 0}, nil},
+		"repeated sid":			{nil, nil, nil, {2, 2, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}, nil},
+		"factories swapped":	{nil, nil, nil, nil, {2, 1, 1, -1, 1, 0, 0, 1, 0, -1, 0, 0, 0}},
+		"factory not factory":	{nil, nil, nil, nil, {2, 0, 0, -1, 0, 0, 0, 1, 1, -1, 1, 0, 0}},
+		"missing factory":		{nil, nil, nil, nil, {1, 1, 0, -1, 0, 0, 0}},
+		"shared cell":			{nil, nil, nil, nil, {2, 1, 0, -1, 0, 0, 0, 1, 1, -1, 0, 0, 0}},
+	}
+
+	flatten := func(parts [][]int) []int {
+		var ret []int
+		for i, part := range parts {
+			if part == nil {
+				part = good[i]
+			}
+			ret = append(ret, part...)
+		}
+		return ret
+	}
+
+	if _, err := FrameFromSnapshot(snapshot_of_vals(flatten(good)...)); err != nil {
+		t.Fatalf("Good snapshot: %v", err)
+	}
+
+	for name, parts := range bad {
+		if _, err := FrameFromSnapshot(snapshot_of_vals(flatten(parts)...)); err == nil {
+			t.Errorf("Bad snapshot (%s): no error", name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package sim
+
+// Read-only access to the state, for Go code outside the package (analysis, Go bots).
+// Everything returned is a copy, so callers can't break the game by changing it.
+//
+// A Frame doesn't know whether the game is bounded, so its ...At() methods always wrap.
+// In a bounded game, check OnMap() first, or use the Game's versions, which treat
+// cells off the map as empty.
+
+func (self *Frame) HaliteAt(x, y int) int {
+	return self.halite[mod(x, self.Width())][mod(y, self.Height())]		// Wraps (see above). -1 is an obstacle.
+}
+
+func (self *Frame) OnMap(x, y int) bool {
+	return self.on_map(x, y)
+}
+
+func (self *Frame) Halite() [][]int {
+
+	// The whole grid, indexed [x][y].
+
+	ret := make_2d_int_array(self.Width(), self.Height())
+	for x := 0; x < self.Width(); x++ {
+		copy(ret[x], self.halite[x])
+	}
+	return ret
+}
+
+func (self *Frame) Budget(pid int) int {
+	return self.budgets[pid]
+}
+
+func (self *Frame) Deposited(pid int) int {
+	return self.deposited[pid]
+}
+
+func (self *Frame) Ship(sid int) (Ship, bool) {
+	if sid < 0 || sid >= len(self.ships) || self.ships[sid] == nil {
+		return Ship{}, false
+	}
+	return *self.ships[sid], true
+}
+
+func (self *Frame) Ships() []Ship {
+
+	// All live ships, in sid order.
+
+	var ret []Ship
+	for _, ship := range self.ships {
+		if ship != nil {
+			ret = append(ret, *ship)
+		}
+	}
+	return ret
+}
+
+func (self *Frame) ShipsOf(pid int) []Ship {
+	var ret []Ship
+	for _, ship := range self.ships {
+		if ship != nil && ship.Owner == pid {
+			ret = append(ret, *ship)
+		}
+	}
+	return ret
+}
+
+func (self *Frame) ShipAt(x, y int) (Ship, bool) {
+	x, y = mod(x, self.Width()), mod(y, self.Height())
+	for _, ship := range self.ships {
+		if ship != nil && ship.X == x && ship.Y == y {
+			return *ship, true
+		}
+	}
+	return Ship{}, false
+}
+
+func (self *Frame) Dropoffs() []Dropoff {
+
+	// Factories first (in pid order) then the rest, like internally.
+
+	var ret []Dropoff
+	for _, dropoff := range self.dropoffs {
+		ret = append(ret, *dropoff)
+	}
+	return ret
+}
+
+func (self *Frame) DropoffsOf(pid int) []Dropoff {
+
+	// Including the factory, which is first.
+
+	var ret []Dropoff
+	for _, dropoff := range self.dropoffs {
+		if dropoff.Owner == pid {
+			ret = append(ret, *dropoff)
+		}
+	}
+	return ret
+}
+
+func (self *Frame) Factory(pid int) Dropoff {
+	return *self.dropoffs[pid]
+}
+
+func (self *Frame) DropoffAt(x, y int) (Dropoff, bool) {
+	x, y = mod(x, self.Width()), mod(y, self.Height())
+	for _, dropoff := range self.dropoffs {
+		if dropoff.X == x && dropoff.Y == y {
+			return *dropoff, true
+		}
+	}
+	return Dropoff{}, false
+}
+
+// ------------------------------------------------------------------------------------------
+
+func (self *Game) Frame() *Frame {
+	return self.frame.Copy()
+}
+
+func (self *Game) Turn() int {
+	return self.frame.Turn()
+}
+
+func (self *Game) Width() int {
+	return self.frame.Width()
+}
+
+func (self *Game) Height() int {
+	return self.frame.Height()
+}
+
+func (self *Game) Players() int {
+	return self.frame.Players()
+}
+
+func (self *Game) OnMap(x, y int) bool {
+	return self.Constants.BOUNDED == false || self.frame.on_map(x, y)
+}
+
+func (self *Game) HaliteAt(x, y int) int {
+	if self.OnMap(x, y) == false {
+		return 0
+	}
+	return self.frame.HaliteAt(x, y)
+}
+
+func (self *Game) ShipAt(x, y int) (Ship, bool) {
+	if self.OnMap(x, y) == false {
+		return Ship{}, false
+	}
+	return self.frame.ShipAt(x, y)
+}
+
+func (self *Game) DropoffAt(x, y int) (Dropoff, bool) {
+	if self.OnMap(x, y) == false {
+		return Dropoff{}, false
+	}
+	return self.frame.DropoffAt(x, y)
+}
+
+func (self *Game) Deposited(pid int) int {
+	return self.frame.Deposited(pid)
+}
+
+func (self *Game) Ships() []Ship {
+	return self.frame.Ships()
+}
+
+func (self *Game) ShipsOf(pid int) []Ship {
+	return self.frame.ShipsOf(pid)
+}
+
+func (self *Game) DropoffsOf(pid int) []Dropoff {
+	return self.frame.DropoffsOf(pid)
+}
@@ -3,19 +3,48 @@ package sim
 import (
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
 )
 
 func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFrame) {
 
+	// The text version, with the strings as sent by the bots. Errors are printed.
+
 	players := self.frame.Players()
-	width := self.frame.Width()
-	height := self.frame.Height()
 
 	if len(all_player_moves) != players {
 		panic("len(all_player_moves) != players")
 	}
 
+	typed := make([][]Move, players)
+	parse_errors := make([]*MoveError, players)
+
+	for pid, s := range all_player_moves {
+		typed[pid], parse_errors[pid] = ParseMoves(pid, s)
+	}
+
+	s, rf, errors := self.update(typed, parse_errors)
+
+	for _, err := range errors {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Message)
+	}
+
+	return s, rf
+}
+
+func (self *Game) UpdateFromTypedMoves(all_player_moves [][]Move) (string, *ReplayFrame, []*MoveError) {
+
+	// The typed version, indexed by pid; missing players send nothing. Nothing is
+	// printed; instead the errors are returned, at most one per player (each of
+	// whom is killed, as usual).
+
+	return self.update(all_player_moves, nil)
+}
+
+func (self *Game) update(all_player_moves [][]Move, parse_errors []*MoveError) (string, *ReplayFrame, []*MoveError) {
+
+	players := self.frame.Players()
+
 	rf := new(ReplayFrame)
 
 	rf.Cells = make([]*CellUpdate, 0)
@@ -42,102 +71,142 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 		rf.Moves[pid] = make([]*ReplayMove, 0)
 	}
 
-	gens := make(map[int]bool)		// pid --> generating?
-	moves := make(map[int]string)	// sid --> move (n, s, e, w, o, c, "")
+	gens, moves, fails := self.validate_all(all_player_moves, parse_errors)
+	new_frame, events := self.resolve(gens, moves, fails)
 
-	fails := make(map[int]string)	// pid --> reason, or "" if success
+	rf.Events = events
 
-	for pid, s := range all_player_moves {
+	// Some replay stuff...
 
-		tokens := tokens_from_cmd_string(s)
+	for pid := 0; pid < players; pid++ {
+		if fails[pid] != nil {
+			continue
+		}
+		if gens[pid] {
+			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{Type: "g"})
+		}
+	}
 
-		command := ""				// g, c, m
-		sid := -1
+	for _, sid := range sorted_sids(moves) {
 
-		TokenLoop:
-		for _, token := range tokens {
+		move := moves[sid]
+		pid := self.frame.ships[sid].Owner
 
-			if command == "" {
+		if fails[pid] != nil {
+			continue
+		}
 
-				command = token
+		if move == "c" {
+			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{
+				Type: "c",
+				Sid: sid,
+			})
+		} else if move != "" {
+			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{
+				Type: "m",
+				Sid: sid,
+				Direction: move,
+			})
+		}
+	}
 
-				if command != "g" && command != "m" && command != "c" {
-					fails[pid] = fmt.Sprintf("Bot %v sent unknown command \"%s\"", pid, command)
-					break TokenLoop
-				}
+	rf.Cells = make_cell_updates(self.frame, new_frame)
 
-				if command == "g" {
-					if gens[pid] {
-						fails[pid] = fmt.Sprintf("Bot %v sent 2 or more generate commands", pid)
-						break TokenLoop
-					}
-					gens[pid] = true
-					command = ""
-				}
-				continue
-			}
+	for pid := 0; pid < players; pid++ {
+		rf.Energy[pid] = new_frame.budgets[pid]
+	}
 
-			if sid == -1 {
+	for pid := 0; pid < players; pid++ {
+		rf.Deposited[pid] = new_frame.deposited[pid]
+	}
 
-				var err error
-				sid, err = strconv.Atoi(token)
+	s := make_bot_update_string(self.frame, new_frame)
+	self.make_player_updates(self.frame, new_frame)
+	self.frame = new_frame
+	return s, rf, error_list(fails, players)
+}
 
-				if err != nil {
-					fails[pid] = err.Error()
-					break TokenLoop
-				}
+// ------------------------------------------------------------------------------------------
+// The forward model, for search. Clone the game, then Simulate() on the clone, which
+// uses exactly the same rules as UpdateFromMoves() but makes no replay frame or update
+// string, and prints nothing.
 
-				if sid >= len(self.frame.ships) || sid < 0 || self.frame.ships[sid] == nil {
-					fails[pid] = fmt.Sprintf("Bot %v sent command for non-existent ship %d", pid, sid)
-					break TokenLoop
-				}
+func (self *Game) Clone() *Game {
 
-				// So the sid is a valid ship...
+	// The Constants are shared, not copied; nothing changes them during a game.
 
-				ship := self.frame.ships[sid]
+	return &Game{
+		Constants: self.Constants,
+		Rules: self.Rules,
+		frame: self.frame.Copy(),
+		initial_halite: self.initial_halite,		// Never changed, so can be shared
+		obstacles: self.obstacles,
+	}
+}
 
-				if ship.Owner != pid {
-					fails[pid] = fmt.Sprintf("Bot %v sent command for ship %d owned by player %d", pid, sid, ship.Owner)
-					break TokenLoop
-				}
+func (self *Game) Simulate(all_player_moves [][]Move) (*Frame, []*ReplayEvent, []*MoveError) {
 
-				// So the ship is indeed owned by the player...
+	// Advances the game by 1 turn. all_player_moves is indexed by pid; missing
+	// players send nothing. As in a real game, a player sending any invalid move
+	// is killed. Returns the new frame (which is the game's own, not a copy), the
+	// spawn / shipwreck / construct events, and the errors, if any.
 
-				if moves[sid] != "" {
-					fails[pid] = fmt.Sprintf("Bot %v sent 2 or more commands for ship %d", pid, sid)
-					break TokenLoop
-				}
+	gens, moves, fails := self.validate_all(all_player_moves, nil)
+	new_frame, events := self.resolve(gens, moves, fails)
+	self.frame = new_frame
+	return new_frame, events, error_list(fails, new_frame.Players())
+}
 
-				if command == "c" {
+// ------------------------------------------------------------------------------------------
 
-					for _, dropoff := range self.frame.dropoffs {
-						if dropoff.X == ship.X && dropoff.Y == ship.Y {
-							fails[pid] = fmt.Sprintf("Bot %v sent construct command from ship %d over a structure", pid, sid)
-							break TokenLoop
-						}
-					}
+func (self *Game) validate_all(all_player_moves [][]Move, parse_errors []*MoveError) (map[int]bool, map[int]string, map[int]*MoveError) {
 
-					moves[sid] = "c"
-					command = ""
-					sid = -1
-				}
-				continue
-			}
+	// Returns gens (pid --> generating?), moves (sid --> n, s, e, w, o, c, "")
+	// and fails (pid --> reason, or nil if success). A parse error only counts
+	// if the moves before it were valid, since those came first.
 
-			direction := token
+	if len(all_player_moves) > self.frame.Players() {
+		panic("len(all_player_moves) > players")
+	}
 
-			if direction != "n" && direction != "s" && direction != "e" && direction != "w" && direction != "o" {
-				fails[pid] = fmt.Sprintf("Bot %v sent unknown direction \"%s\"", pid, direction)
-				break TokenLoop
-			}
+	gens := make(map[int]bool)
+	moves := make(map[int]string)
+	fails := make(map[int]*MoveError)
 
-			moves[sid] = direction
+	for pid, player_moves := range all_player_moves {
 
-			command = ""
-			sid = -1
+		gens[pid], fails[pid] = self.validate_moves(pid, player_moves, moves)
+
+		if fails[pid] == nil && parse_errors != nil {
+			fails[pid] = parse_errors[pid]
 		}
 	}
 
+	return gens, moves, fails
+}
+
+func error_list(fails map[int]*MoveError, players int) []*MoveError {
+	var ret []*MoveError
+	for pid := 0; pid < players; pid++ {
+		if fails[pid] != nil {
+			ret = append(ret, fails[pid])
+		}
+	}
+	return ret
+}
+
+func (self *Game) resolve(gens map[int]bool, moves map[int]string, fails map[int]*MoveError) (*Frame, []*ReplayEvent) {
+
+	// Given validated moves, make the next frame. Players with fails are killed, and
+	// any new fails (going over budget) are added to the map. The current frame is
+	// not changed, nor is self.frame set.
+
+	players := self.frame.Players()
+	width := self.frame.Width()
+	height := self.frame.Height()
+
+	events := make([]*ReplayEvent, 0)
+
 	// ---------------------------------------------------------------
 
 	new_frame := self.frame.Copy()
@@ -163,14 +232,16 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 		}
 	}
 
-	for sid, move := range moves {
+	for _, sid := range sorted_sids(moves) {
+
+		move := moves[sid]
 
 		if move == "c" {
 
 			ship := new_frame.ships[sid]		// Note we already checked this sid exists and is not nil.
 			pid := ship.Owner
 
-			new_frame.budgets[pid] -= self.Constants.DROPOFF_COST
+			new_frame.budgets[pid] -= self.Rules.ConstructionCost(self, ship)
 			new_frame.budgets[pid] += ship.Halite
 			new_frame.budgets[pid] += new_frame.halite[ship.X][ship.Y]
 		}
@@ -178,21 +249,15 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 	for pid := 0; pid < players; pid++ {
 		if new_frame.budgets[pid] < 0 {
-			fails[pid] = fmt.Sprintf("Bot %v went over budget", pid)
+			fails[pid] = move_error(pid, -1, -1, OVER_BUDGET, "Bot %v went over budget", pid)
 		}
 	}
 
-	// Print info on fails and kill player...
+	// Kill players with fails...
 
 	for pid := 0; pid < players; pid++ {
-
-		if fails[pid] != "" {
-
-			fmt.Fprintf(os.Stderr, "%s\n", fails[pid])
-
-			if new_frame.IsAlive(pid) {
-				new_frame.Kill(pid, -2)
-			}
+		if fails[pid] != nil && new_frame.IsAlive(pid) {
+			new_frame.Kill(pid, -2)
 		}
 	}
 
@@ -220,7 +285,9 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 	// Make dropoffs...
 
-	for sid, move := range moves {
+	for _, sid := range sorted_sids(moves) {
+
+		move := moves[sid]
 
 		if move != "c" {
 			continue
@@ -252,7 +319,7 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 		new_frame.halite[ship.X][ship.Y] = 0	// Do this after the above lol
 
-		rf.Events = append(rf.Events, &ReplayEvent{
+		events = append(events, &ReplayEvent{
 			Sid: ship.Sid,
 			Location: &Position{ship.X, ship.Y},
 			Owner: ship.Owner,
@@ -264,7 +331,9 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 	ship_positions := make(map[Position][]*Ship)
 
-	for sid, move := range moves {
+	for _, sid := range sorted_sids(moves) {
+
+		move := moves[sid]
 
 		ship := new_frame.ships[sid]		// Note we already checked this sid exists, but it may have been made nil above.
 
@@ -272,14 +341,22 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 			continue
 		}
 
-		mcr := self.Constants.MOVE_COST_RATIO
-		if ship.Inspired { mcr = self.Constants.INSPIRED_MOVE_COST_RATIO }	// See note far below on .Inspiration
+		// Moving into an obstacle, or off the edge of a bounded map, is the same as staying still...
+
+		if move != "" && move != "o" && move != "c" {
+			dx, dy := string_to_dxdy(move)
+			if new_frame.can_enter(ship.X + dx, ship.Y + dy, self.Constants.BOUNDED) == false {
+				move = "o"
+			}
+		}
+
+		move_cost := self.Rules.MoveCost(self, ship, new_frame.halite[ship.X][ship.Y])
 
-		if ship.Halite >= new_frame.halite[ship.X][ship.Y] / mcr {			// We can move
+		if ship.Halite >= move_cost {										// We can move
 
 			if move != "" && move != "o" && move != "c" {					// We did move
 
-				ship.Halite -= new_frame.halite[ship.X][ship.Y] / mcr
+				ship.Halite -= move_cost
 
 				dx, dy := string_to_dxdy(move)
 
@@ -311,34 +388,8 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 	// Delete ships that collide...
 
-	collision_points := make(map[Position]bool)
-
-	for point, ships_here := range ship_positions {
-
-		x, y := point.X, point.Y
-
-		if len(ships_here) == 1 && attempted_spawn_points[Position{x, y}] == false {
-			continue
-		}
-
-		// Collision...
-
-		collision_points[Position{x, y}] = true
-
-		var wreckedsids []int
-
-		for _, ship := range ships_here {
-			new_frame.ships[ship.Sid] = nil
-			new_frame.halite[x][y] += ship.Halite			// Dump the halite on the ground.
-			wreckedsids = append(wreckedsids, ship.Sid)
-		}
-
-		rf.Events = append(rf.Events, &ReplayEvent{
-			Location: &Position{x, y},
-			WreckedSids: wreckedsids,
-			Type: "shipwreck",
-		})
-	}
+	collision_points, collision_events := self.Rules.Collide(self, new_frame, ship_positions, attempted_spawn_points)
+	events = append(events, collision_events...)
 
 	// Deliveries...
 
@@ -405,7 +456,7 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 			new_frame.ships = append(new_frame.ships, ship)
 
-			rf.Events = append(rf.Events, &ReplayEvent{
+			events = append(events, &ReplayEvent{
 				Energy: 0,
 				Sid: sid,
 				Location: &Position{x, y},
@@ -417,8 +468,6 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 
 	// Mining...
 
-	ibm := int(self.Constants.INSPIRED_BONUS_MULTIPLIER)		// May be a float in replays but we'll only accept ints
-
 	for sid, ship := range new_frame.ships {
 
 		if ship == nil {
@@ -432,89 +481,35 @@ func (self *Game) UpdateFromMoves(all_player_moves []string) (string, *ReplayFra
 		old_ship := self.frame.ships[sid]
 
 		if old_ship.X == ship.X && old_ship.Y == ship.Y {
-
-			// Normal mining...
-
-			exrat := self.Constants.EXTRACT_RATIO
-			if ship.Inspired { exrat = self.Constants.INSPIRED_EXTRACT_RATIO }		// See note below on .Inspiration
-
-			amount_to_mine := (new_frame.halite[ship.X][ship.Y] + exrat - 1) / exrat
-
-			if amount_to_mine + ship.Halite >= self.Constants.MAX_ENERGY {
-				amount_to_mine = self.Constants.MAX_ENERGY - ship.Halite
-			}
-
-			ship.Halite += amount_to_mine
-			new_frame.halite[ship.X][ship.Y] -= amount_to_mine
-
-			// Inspired bonus... (doesn't remove halite from ground)
-
-			if ship.Inspired {				// See note below on .Inspiration
-
-				inspired_bonus := amount_to_mine * ibm
-
-				if inspired_bonus + ship.Halite >= self.Constants.MAX_ENERGY {
-					inspired_bonus = self.Constants.MAX_ENERGY - ship.Halite
-				}
-
-				ship.Halite += inspired_bonus
-			}
+			taken, gained := self.Rules.Mine(self, ship, new_frame.halite[ship.X][ship.Y])
+			new_frame.halite[ship.X][ship.Y] -= taken
+			ship.Halite += gained
 		}
 	}
 
+	// Anything else, e.g. regrowth...
+
+	events = append(events, self.Rules.EndOfTurn(self, new_frame)...)
+
 	// Fix inspiration of the new frame's ships.
 	//
 	// Up till now, they had the previous frame's values, which meant
 	// it was OK to use the new objects' .Inspired values, above.
 
-	new_frame.fix_inspiration(
-		self.Constants.INSPIRATION_RADIUS,
-		self.Constants.INSPIRATION_SHIP_COUNT)
-
-	// Some replay stuff...
-
-	for pid := 0; pid < players; pid++ {
-		if fails[pid] != "" {
-			continue
-		}
-		if gens[pid] {
-			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{Type: "g"})
-		}
-	}
-
-	for sid, move := range moves {
+	self.Rules.Inspire(self, new_frame)
 
-		pid := self.frame.ships[sid].Owner
-
-		if fails[pid] != "" {
-			continue
-		}
-
-		if move == "c" {
-			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{
-				Type: "c",
-				Sid: sid,
-			})
-		} else if move != "" {
-			rf.Moves[pid] = append(rf.Moves[pid], &ReplayMove{
-				Type: "m",
-				Sid: sid,
-				Direction: move,
-			})
-		}
-	}
+	return new_frame, events
+}
 
-	rf.Cells = make_cell_updates(self.frame, new_frame)
+func sorted_sids(moves map[int]string) []int {
 
-	for pid := 0; pid < players; pid++ {
-		rf.Energy[pid] = new_frame.budgets[pid]
-	}
+	// Iterating over the moves map directly would happen in random order, which made
+	// e.g. dropoff ids vary from run to run when several were built on one turn.
 
-	for pid := 0; pid < players; pid++ {
-		rf.Deposited[pid] = new_frame.deposited[pid]
+	var ret []int
+	for sid := range moves {
+		ret = append(ret, sid)
 	}
-
-	s := make_bot_update_string(self.frame, new_frame)
-	self.frame = new_frame
-	return s, rf
+	sort.Ints(ret)
+	return ret
 }
@@ -10,6 +10,19 @@ func make_bot_update_string(old, current *Frame) string {
 
 	// The string to send to a bot after the turn (before the next turn, whatever)
 
+	return make_update_string(current, old.halite, nil, -1)
+}
+
+func make_update_string(current *Frame, known [][]int, visible [][]bool, viewer int) string {
+
+	// Sends the cells that differ from known. If visible isn't nil, it's the fog of
+	// war version for player viewer: other players' ships and the cells are only
+	// sent where visible. The caller should update its known map afterwards.
+
+	can_see := func(x, y int) bool {
+		return visible == nil || visible[x][y]
+	}
+
 	players := current.Players()
 	width := current.Width()
 	height := current.Height()
@@ -25,6 +38,10 @@ func make_bot_update_string(old, current *Frame) string {
 			continue
 		}
 
+		if ship.Owner != viewer && can_see(ship.X, ship.Y) == false {
+			continue
+		}
+
 		ship_counts[ship.Owner] += 1
 	}
 
@@ -57,6 +74,10 @@ func make_bot_update_string(old, current *Frame) string {
 				continue
 			}
 
+			if ship.Owner != viewer && can_see(ship.X, ship.Y) == false {
+				continue
+			}
+
 			lines = append(lines, fmt.Sprintf("%d %d %d %d", ship.Sid, ship.X, ship.Y, ship.Halite))
 		}
 
@@ -80,7 +101,7 @@ func make_bot_update_string(old, current *Frame) string {
 
 	for x := 0; x < width; x++ {
 		for y := 0; y < height; y++ {
-			if current.halite[x][y] != old.halite[x][y] {
+			if current.halite[x][y] != known[x][y] && can_see(x, y) {
 				update_lines = append(update_lines, fmt.Sprintf("%d %d %d", x, y, current.halite[x][y]))
 			}
 		}
@@ -137,3 +158,44 @@ func tokens_from_cmd_string(s string) []string {
 
 	return strings.Fields(s)
 }
+
+func fog_visibility(frame *Frame, pid int, radius int, bounded bool) [][]bool {
+
+	// Cells within radius of any of the player's ships or structures.
+
+	width := frame.Width()
+	height := frame.Height()
+
+	ret := make([][]bool, width)
+	for x := 0; x < width; x++ {
+		ret[x] = make([]bool, height)
+	}
+
+	var sources []Position
+
+	for _, ship := range frame.ships {
+		if ship != nil && ship.Owner == pid {
+			sources = append(sources, Position{ship.X, ship.Y})
+		}
+	}
+
+	for _, dropoff := range frame.dropoffs {
+		if dropoff.Owner == pid {
+			sources = append(sources, Position{dropoff.X, dropoff.Y})
+		}
+	}
+
+	for _, source := range sources {
+		for dx := -radius; dx <= radius; dx++ {
+			reach := radius - abs(dx)
+			for dy := -reach; dy <= reach; dy++ {
+				if bounded && frame.on_map(source.X + dx, source.Y + dy) == false {
+					continue
+				}
+				ret[mod(source.X + dx, width)][mod(source.Y + dy, height)] = true
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func new_test_game(players int) *Game {
+	constants := NewConstants(players, 32, 32, 400, 42)
+	return test_game(constants, MapGenOfficial(players, 32, 32, constants.INITIAL_ENERGY, 42))
+}
+
+func test_game(constants *Constants, frame *Frame) *Game {
+	game := NewGame(constants)
+	game.UseFrame(frame)
+	return game
+}
+
+func test_frame(t *testing.T, frame *Frame, ships []Ship, cells map[Position]int) *Frame {
+
+	// The frame with just the given ships, and some cells changed. This is made the
+	// way a bot would make it, from an update, so tests needn't reach inside a Frame.
+
+	lines := []string{strconv.Itoa(frame.Turn())}
+
+	for pid := 0; pid < frame.Players(); pid++ {
+
+		var ship_lines []string
+
+		for _, ship := range ships {
+			if ship.Owner == pid {
+				ship_lines = append(ship_lines, fmt.Sprintf("%d %d %d %d", ship.Sid, ship.X, ship.Y, ship.Halite))
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf("%d %d 0 %d", pid, len(ship_lines), frame.Budget(pid)))
+		lines = append(lines, ship_lines...)
+	}
+
+	lines = append(lines, strconv.Itoa(len(cells)))
+
+	for pos, val := range cells {
+		lines = append(lines, fmt.Sprintf("%d %d %d", pos.X, pos.Y, val))
+	}
+
+	ret, err := FrameFromUpdate(frame, strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatalf("test_frame: %v", err)
+	}
+
+	return ret
+}
+
+func random_moves(game *Game, rng *rand.Rand) [][]Move {
+
+	ret := make([][]Move, game.Players())
+
+	for pid := 0; pid < game.Players(); pid++ {
+		for _, ship := range game.ShipsOf(pid) {
+			if rng.Intn(50) == 0 {
+				ret[pid] = append(ret[pid], Move{Type: "c", Sid: ship.Sid})
+			} else {
+				ret[pid] = append(ret[pid], Move{Type: "m", Sid: ship.Sid, Direction: string("nsewo"[rng.Intn(5)])})
+			}
+		}
+		if rng.Intn(3) == 0 {
+			ret[pid] = append(ret[pid], Move{Type: "g"})
+		}
+	}
+
+	return ret
+}
+
+func TestSimulateMatchesUpdate(t *testing.T) {
+
+	// The forward model must give exactly what the engine gives.
+
+	game := new_test_game(4)
+	rng := rand.New(rand.NewSource(1))
+
+	for turn := 0; turn < 200; turn++ {
+
+		moves := random_moves(game, rng)
+
+		var strs []string
+		for _, player_moves := range moves {
+			strs = append(strs, MovesString(player_moves))
+		}
+
+		clone := game.Clone()
+		clone.Simulate(moves)
+		game.UpdateFromMoves(strs)
+
+		if reflect.DeepEqual(clone.frame, game.frame) == false {
+			t.Fatalf("Turn %d: Simulate() and UpdateFromMoves() disagree", turn)
+		}
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+
+	game := new_test_game(2)
+	game.UpdateFromMoves([]string{"g", "g"})
+
+	before := game.Snapshot()
+
+	clone := game.Clone()
+	clone.Simulate([][]Move{{{Type: "m", Sid: 0, Direction: "n"}}, {{Type: "g"}}})
+
+	if game.Snapshot() != before {
+		t.Errorf("Simulate() on a clone changed the original")
+	}
+	if clone.Turn() != game.Turn() + 1 {
+		t.Errorf("Clone at turn %d, expected %d", clone.Turn(), game.Turn() + 1)
+	}
+}
+
+func TestParseMoves(t *testing.T) {
+
+	tests := []struct {
+		s			string
+		moves		[]Move
+		fail		string
+	}{
+		{"", nil, ""},
+		{"g c 12 m 14 e", []Move{{Type: "g"}, {Type: "c", Sid: 12}, {Type: "m", Sid: 14, Direction: "e"}}, ""},
+		{"gc12m14e", []Move{{Type: "g"}, {Type: "c", Sid: 12}, {Type: "m", Sid: 14, Direction: "e"}}, ""},
+		{"m 3", []Move{{Type: "m", Sid: 3}}, ""},
+		{"m 3 n x", []Move{{Type: "m", Sid: 3, Direction: "n"}}, UNKNOWN_COMMAND},
+		{"m x", nil, BAD_SYNTAX},
+	}
+
+	for _, test := range tests {
+		moves, err := ParseMoves(0, test.s)
+		fail := ""
+		if err != nil {
+			fail = err.Kind
+		}
+		if reflect.DeepEqual(moves, test.moves) == false || fail != test.fail {
+			t.Errorf("ParseMoves(%q): got %v %q, expected %v %q", test.s, moves, fail, test.moves, test.fail)
+		}
+	}
+}
+
+func TestMoveErrors(t *testing.T) {
+
+	game := new_test_game(2)
+	game.UpdateFromMoves([]string{"g", "g"})
+
+	// Player 1 tries to move player 0's ship (sid 0)...
+
+	_, _, errors := game.UpdateFromTypedMoves([][]Move{
+		{{Type: "m", Sid: 0, Direction: "n"}},
+		{{Type: "m", Sid: 1, Direction: "s"}, {Type: "m", Sid: 0, Direction: "n"}},
+	})
+
+	if len(errors) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(errors))
+	}
+
+	err := errors[0]
+
+	if err.Pid != 1 || err.Index != 1 || err.Sid != 0 || err.Kind != NOT_OWNER {
+		t.Errorf("Got %+v", err)
+	}
+
+	if game.IsAlive(0) == false || game.IsAlive(1) {
+		t.Errorf("Expected only player 1 to be killed")
+	}
+
+	// A move with no direction still counts as a command for that ship...
+
+	_, _, errors = game.UpdateFromTypedMoves([][]Move{
+		{{Type: "m", Sid: 0}, {Type: "m", Sid: 0, Direction: "n"}},
+		nil,
+	})
+
+	if len(errors) != 1 || errors[0].Index != 1 || errors[0].Kind != MULTIPLE_COMMANDS {
+		t.Errorf("Got %v, expected a MULTIPLE_COMMANDS error", errors)
+	}
+}
@@ -31,6 +31,13 @@ func mod(x, n int) int {
 	return (x % n + n) % n
 }
 
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 func make_2d_float_array(width, height int) [][]float64 {
 	ret := make([][]float64, width)
 	for x := 0; x < width; x++ {
@@ -46,3 +53,22 @@ func make_2d_int_array(width, height int) [][]int {
 	}
 	return ret
 }
+
+func (self *Frame) on_map(x, y int) bool {
+	return x >= 0 && x < self.Width() && y >= 0 && y < self.Height()
+}
+
+func (self *Frame) distance(x1, y1, x2, y2 int, bounded bool) int {
+
+	// Manhattan distance, going round the edges unless the map is bounded.
+
+	dx := abs(x2 - x1)
+	dy := abs(y2 - y1)
+
+	if bounded == false {
+		dx = min(dx, self.Width() - dx)
+		dy = min(dy, self.Height() - dy)
+	}
+
+	return dx + dy
+}
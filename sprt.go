@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"./sim"
+)
+
+// Sequential probability ratio test of bot A against bot B. H0 is that A is elo0
+// stronger than B, H1 that A is elo1 stronger. We keep playing until the log
+// likelihood ratio crosses one of the bounds given by alpha and beta.
+//
+// Each game is treated as a Bernoulli trial which A wins with probability s0 under
+// H0 and s1 under H1 (the expected scores for those Elo differences). Draws, i.e.
+// equal ranks, count as half a win and half a loss.
+
+type SPRTState struct {
+	Wins						int
+	Draws						int
+	Losses						int
+}
+
+func (self *SPRTState) Games() int {
+	return self.Wins + self.Draws + self.Losses
+}
+
+func (self *SPRTState) mean_and_variance() (float64, float64) {
+
+	n := float64(self.Games())
+
+	mean := (float64(self.Wins) + float64(self.Draws) / 2) / n
+
+	variance := (float64(self.Wins) * (1 - mean) * (1 - mean) +
+				 float64(self.Draws) * (0.5 - mean) * (0.5 - mean) +
+				 float64(self.Losses) * mean * mean) / n
+
+	return mean, variance
+}
+
+func (self *SPRTState) LLR(elo0, elo1 float64) float64 {
+
+	s0, s1 := expected_score(elo0), expected_score(elo1)
+
+	wins := float64(self.Wins) + float64(self.Draws) / 2
+	losses := float64(self.Losses) + float64(self.Draws) / 2
+
+	return wins * math.Log(s1 / s0) + losses * math.Log((1 - s1) / (1 - s0))
+}
+
+func (self *SPRTState) Elo() (estimate, lower, upper float64) {
+
+	// With a 95% confidence interval.
+
+	if self.Games() == 0 {
+		return 0, math.Inf(-1), math.Inf(1)
+	}
+
+	mean, variance := self.mean_and_variance()
+	margin := 1.96 * math.Sqrt(variance / float64(self.Games()))
+
+	return elo_from_score(mean), elo_from_score(mean - margin), elo_from_score(mean + margin)
+}
+
+func expected_score(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo / 400))
+}
+
+func elo_from_score(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1 / score - 1)
+}
+
+// -----------------------------------------------------------------------------------------
+
+const SPRT_MAX_FAILURES = 3			// Pairs of games in a row, before we give up
+
+func sprt(opts *Options) {
+
+	if len(opts.Botlist) != 2 {
+		fmt.Fprintf(os.Stderr, "SPRT needs exactly 2 bots\n")
+		return
+	}
+
+	if (opts.SPRTAlpha > 0 && opts.SPRTAlpha < 1 && opts.SPRTBeta > 0 && opts.SPRTBeta < 1) == false {	// So NaN fails too
+		fmt.Fprintf(os.Stderr, "--alpha and --beta must be between 0 and 1\n")
+		return
+	}
+
+	if opts.SPRTElo0 >= opts.SPRTElo1 {
+		fmt.Fprintf(os.Stderr, "--elo0 must be less than --elo1\n")
+		return
+	}
+
+	a, b := opts.Botlist[0], opts.Botlist[1]
+
+	lower := math.Log(opts.SPRTBeta / (1 - opts.SPRTAlpha))
+	upper := math.Log((1 - opts.SPRTBeta) / opts.SPRTAlpha)
+
+	fmt.Fprintf(os.Stderr, "SPRT: elo0 = %v, elo1 = %v, alpha = %v, beta = %v, LLR bounds [%.2f, %.2f]\n",
+		opts.SPRTElo0, opts.SPRTElo1, opts.SPRTAlpha, opts.SPRTBeta, lower, upper)
+
+	state := new(SPRTState)
+	llr := 0.0
+	failures := 0				// In a row
+	aborted := false
+
+	// Each seed is played twice, with the seats swapped, which cancels out most of the map's luck.
+	// So if either game of the pair fails, neither counts.
+
+	for seed := opts.Seed; ; seed++ {
+
+		size := sim.SizeFromSeed(seed)			// i.e. the normal distribution of sizes, unless asked otherwise
+		if len(opts.Sizes) > 0 {
+			size = opts.Sizes[int(seed) % len(opts.Sizes)]
+		}
+
+		var results []*GameResult
+
+		for a_seat := 0; a_seat < 2; a_seat++ {
+
+			seating := []string{a, b}
+			if a_seat == 1 {
+				seating = []string{b, a}
+			}
+
+			result, err := run_engine(opts, seed, size, size, seating)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Game failed: %v\n", err)
+				break
+			}
+
+			results = append(results, result)
+		}
+
+		if len(results) < 2 {
+			failures++
+			if failures >= SPRT_MAX_FAILURES {
+				fmt.Fprintf(os.Stderr, "Giving up after %d failed pairs in a row\n", failures)
+				aborted = true
+				break
+			}
+			continue
+		}
+
+		failures = 0
+
+		for a_seat, result := range results {
+
+			rank_a := result.Players[a_seat].Rank
+			rank_b := result.Players[1 - a_seat].Rank
+
+			if rank_a < rank_b {
+				state.Wins++
+			} else if rank_a > rank_b {
+				state.Losses++
+			} else {
+				state.Draws++
+			}
+		}
+
+		llr = state.LLR(opts.SPRTElo0, opts.SPRTElo1)
+
+		fmt.Fprintf(os.Stderr, "Games: %d  W/D/L: %d/%d/%d  LLR: %.3f [%.2f, %.2f]\n",
+			state.Games(), state.Wins, state.Draws, state.Losses, llr, lower, upper)
+
+		if llr <= lower || llr >= upper {
+			break
+		}
+
+		if opts.SPRTMaxGames > 0 && state.Games() >= opts.SPRTMaxGames {
+			break
+		}
+	}
+
+	estimate, elo_lower, elo_upper := state.Elo()
+
+	verdict := "inconclusive (hit --max-games)"
+	if aborted {
+		verdict = "inconclusive (games kept failing)"
+	} else if llr >= upper {
+		verdict = "H1 accepted (A is stronger by at least elo1)"
+	} else if llr <= lower {
+		verdict = "H0 accepted (A is not stronger by elo1)"
+	}
+
+	fmt.Printf("A: %s\n", a)
+	fmt.Printf("B: %s\n", b)
+	fmt.Printf("Result: %s\n", verdict)
+	fmt.Printf("Games: %d  W/D/L: %d/%d/%d  LLR: %.3f\n", state.Games(), state.Wins, state.Draws, state.Losses, llr)
+	fmt.Printf("Elo difference (A - B): %.1f  95%% CI [%.1f, %.1f]\n", estimate, elo_lower, elo_upper)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func close_to(a, b float64) bool {
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return a == b
+	}
+	return math.Abs(a - b) < 1e-7
+}
+
+func TestExpectedScore(t *testing.T) {
+
+	tests := []struct {
+		elo, score		float64
+	}{
+		{0, 0.5},
+		{400, 10.0 / 11},			// 10 times as likely to win as lose
+		{-400, 1.0 / 11},
+		{200, 0.7597469266},
+		{-200, 0.2402530734},
+	}
+
+	for _, test := range tests {
+		if score := expected_score(test.elo); close_to(score, test.score) == false {
+			t.Errorf("expected_score(%v): got %v, expected %v", test.elo, score, test.score)
+		}
+	}
+}
+
+func TestEloFromScore(t *testing.T) {
+
+	tests := []struct {
+		score, elo		float64
+	}{
+		{0.5, 0},
+		{10.0 / 11, 400},
+		{0.75, 400 * math.Log10(3)},		// i.e. 190.85
+		{0.25, -400 * math.Log10(3)},
+		{0, math.Inf(-1)},
+		{1, math.Inf(1)},
+		{-0.1, math.Inf(-1)},
+	}
+
+	for _, test := range tests {
+		if elo := elo_from_score(test.score); close_to(elo, test.elo) == false {
+			t.Errorf("elo_from_score(%v): got %v, expected %v", test.score, elo, test.elo)
+		}
+	}
+}
+
+func TestLLR(t *testing.T) {
+
+	tests := []struct {
+		state			SPRTState
+		elo0, elo1		float64
+		llr				float64
+	}{
+		{SPRTState{10, 0, 0}, 0, 400, 10 * math.Log(20.0 / 11)},		// s1 / s0 = (10 / 11) / 0.5
+		{SPRTState{0, 0, 10}, 0, 400, 10 * math.Log(2.0 / 11)},			// (1 - s1) / (1 - s0) = (1 / 11) / 0.5
+		{SPRTState{0, 2, 0}, 0, 400, math.Log(40.0 / 121)},				// Half a win and half a loss each
+		{SPRTState{10, 0, 10}, -5, 5, 0},								// Symmetric, so no evidence either way
+		{SPRTState{0, 0, 0}, 0, 20, 0},
+	}
+
+	for _, test := range tests {
+		if llr := test.state.LLR(test.elo0, test.elo1); close_to(llr, test.llr) == false {
+			t.Errorf("%+v LLR(%v, %v): got %v, expected %v", test.state, test.elo0, test.elo1, llr, test.llr)
+		}
+	}
+}
+
+func TestElo(t *testing.T) {
+
+	tests := []struct {
+		state					SPRTState
+		estimate, lower, upper	float64
+	}{
+		{SPRTState{0, 0, 0}, 0, math.Inf(-1), math.Inf(1)},
+		{SPRTState{50, 0, 50}, 0, -68.99005236, 68.99005236},			// Mean 0.5, margin 1.96 * 0.05
+		{SPRTState{60, 20, 20}, 147.19071412, 86.22395123, 218.25322848},	// Mean 0.7, variance 0.16, margin 0.0784
+		{SPRTState{10, 0, 0}, math.Inf(1), math.Inf(1), math.Inf(1)},	// No variance, and a score of 1
+	}
+
+	for _, test := range tests {
+		estimate, lower, upper := test.state.Elo()
+		if close_to(estimate, test.estimate) == false || close_to(lower, test.lower) == false || close_to(upper, test.upper) == false {
+			t.Errorf("%+v Elo(): got %v [%v, %v], expected %v [%v, %v]", test.state, estimate, lower, upper, test.estimate, test.lower, test.upper)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Tournaments are run by calling ourselves once per game, so each game gets a fresh
+// engine (no leftover handlers or processes) and exactly the normal engine loop.
+
+type SeatResult struct {
+	Cmd						string				`json:"cmd"`
+	Seat					int					`json:"seat"`			// i.e. pid
+	Rank					int					`json:"rank"`
+	Score					int					`json:"score"`
+}
+
+type GameResult struct {								// One line of the results store
+	Seed					uint32				`json:"seed"`
+	Width					int					`json:"width"`
+	Height					int					`json:"height"`
+	Players					[]SeatResult		`json:"players"`
+	Replay					string				`json:"replay"`
+}
+
+func (self *GameResult) Key() string {
+	var cmds []string
+	for _, player := range self.Players {
+		cmds = append(cmds, player.Cmd)
+	}
+	return game_key(self.Seed, self.Width, self.Height, cmds)
+}
+
+func game_key(seed uint32, width, height int, cmds []string) string {
+	j, _ := json.Marshal(cmds)			// Bot commands can contain anything, so quote them
+	return fmt.Sprintf("%d %dx%d %s", seed, width, height, string(j))
+}
+
+// -----------------------------------------------------------------------------------------
+
+func run_engine(opts *Options, seed uint32, width, height int, cmds []string) (*GameResult, error) {
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--seed", strconv.FormatUint(uint64(seed), 10),
+		"--width", strconv.Itoa(width),
+		"--height", strconv.Itoa(height),
+		"--replay-directory", opts.Folder,
+		"--results-as-json",
+	}
+
+	if opts.NoReplay { args = append(args, "--no-replay") }
+	if opts.NoCompression { args = append(args, "--no-compression") }
+	if opts.NoTimeout { args = append(args, "--no-timeout") }
+	if opts.Rules != "" { args = append(args, "--rules", opts.Rules) }
+	if opts.Bounded { args = append(args, "--bounded") }
+	if opts.Obstacles > 0 { args = append(args, "--obstacles", strconv.Itoa(opts.Obstacles)) }
+	if opts.Fog > 0 { args = append(args, "--fog", strconv.Itoa(opts.Fog)) }
+	if opts.Regrowth > 0 { args = append(args, "--regrowth", strconv.Itoa(opts.Regrowth), "--regrowth-target", strconv.Itoa(opts.RegrowthTarget)) }
+
+	args = append(args, cmds...)
+
+	var stdout bytes.Buffer
+
+	engine := exec.Command(executable, args...)
+	engine.Stdout = &stdout
+	engine.Stderr = os.Stderr
+
+	err = engine.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	ps := new(PrintedStats)
+
+	err = json.Unmarshal(stdout.Bytes(), ps)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GameResult{
+		Seed: ps.MapSeed,
+		Width: ps.MapWidth,
+		Height: ps.MapHeight,
+		Replay: ps.Replay,
+	}
+
+	for pid := 0; pid < len(cmds); pid++ {
+		result.Players = append(result.Players, SeatResult{
+			Cmd: cmds[pid],				// Not ps.Stats[pid].Cmd, which is the same anyway
+			Seat: pid,
+			Rank: ps.Stats[pid].Rank,
+			Score: ps.Stats[pid].Score,
+		})
+	}
+
+	return result, nil
+}
+
+// -----------------------------------------------------------------------------------------
+
+func load_results(filename string) ([]*GameResult, error) {
+
+	// A missing store is just an empty one.
+
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ret []*GameResult
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64 * 1024), 4 * 1024 * 1024)
+
+	for scanner.Scan() {
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		result := new(GameResult)
+
+		err := json.Unmarshal(scanner.Bytes(), result)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)		// Most likely a line cut short by a crash; let the user fix it
+		}
+
+		ret = append(ret, result)
+	}
+
+	return ret, scanner.Err()
+}
+
+func append_result(filename string, result *GameResult) error {
+
+	f, err := os.OpenFile(filename, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	j, _ := json.Marshal(result)
+	_, err = f.Write(append(j, '\n'))
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// -----------------------------------------------------------------------------------------
+
+func tournament(opts *Options) {
+
+	bots := opts.Botlist
+
+	if len(bots) < 2 {
+		fmt.Fprintf(os.Stderr, "A tournament needs at least 2 bots\n")
+		return
+	}
+
+	if len(opts.Seeds) == 0 {
+		fmt.Fprintf(os.Stderr, "A tournament needs a seed plan, e.g. --seeds 1-50\n")		// So that re-running can resume
+		return
+	}
+
+	sizes := opts.Sizes
+	if len(sizes) == 0 {
+		sizes = []int{32, 40, 48, 56, 64}
+	}
+
+	counts := opts.PlayerCounts
+	if len(counts) == 0 {
+		counts = []int{2, 4}
+	}
+
+	seatings := tournament_seatings(bots, counts)
+
+	previous, err := load_results(opts.Tournament)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return
+	}
+
+	done := make(map[string]bool)
+	for _, result := range previous {
+		done[result.Key()] = true
+	}
+
+	total := len(opts.Seeds) * len(sizes) * len(seatings)
+	n := 0
+
+	for _, seed := range opts.Seeds {
+		for _, size := range sizes {
+			for _, seating := range seatings {
+
+				n++
+
+				if done[game_key(seed, size, size, seating)] {
+					continue
+				}
+
+				fmt.Fprintf(os.Stderr, "Game %d/%d: seed %d, %dx%d: %s\n", n, total, seed, size, size, strings.Join(seating, " vs "))
+
+				result, err := run_engine(opts, seed, size, size, seating)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Game failed: %v\n", err)
+					continue
+				}
+
+				err = append_result(opts.Tournament, result)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					return
+				}
+			}
+		}
+	}
+}
+
+func tournament_seatings(bots []string, counts []int) [][]string {
+
+	// Every group of bots, in every rotation, so that each bot sits in each seat.
+	// Player counts that can't be played are skipped.
+
+	var seatings [][]string
+
+	for _, count := range counts {
+		if count < 2 || count > 4 || count > len(bots) {
+			continue
+		}
+		for _, group := range combinations(len(bots), count) {
+			for rotation := 0; rotation < count; rotation++ {
+				var seating []string
+				for i := 0; i < count; i++ {
+					seating = append(seating, bots[group[(i + rotation) % count]])
+				}
+				seatings = append(seatings, seating)
+			}
+		}
+	}
+
+	return seatings
+}
+
+func combinations(n, k int) [][]int {
+
+	// All ways of choosing k of the integers 0..n-1, in order.
+
+	if k == 0 {
+		return [][]int{nil}
+	}
+
+	var ret [][]int
+
+	for first := 0; first <= n - k; first++ {
+		for _, rest := range combinations(n - first - 1, k - 1) {
+			combo := []int{first}
+			for _, i := range rest {
+				combo = append(combo, first + 1 + i)
+			}
+			ret = append(ret, combo)
+		}
+	}
+
+	return ret
+}
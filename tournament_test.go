@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCombinations(t *testing.T) {
+
+	tests := []struct {
+		n, k			int
+		expected		[][]int
+	}{
+		{4, 2, [][]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}},
+		{3, 3, [][]int{{0, 1, 2}}},
+		{2, 0, [][]int{nil}},
+		{2, 3, nil},
+	}
+
+	for _, test := range tests {
+		if got := combinations(test.n, test.k); reflect.DeepEqual(got, test.expected) == false {
+			t.Errorf("combinations(%d, %d): got %v, expected %v", test.n, test.k, got, test.expected)
+		}
+	}
+}
+
+func TestTournamentSeatings(t *testing.T) {
+
+	got := tournament_seatings([]string{"a", "b", "c"}, []int{2, 4})		// No 4 player games with 3 bots
+
+	expected := [][]string{
+		{"a", "b"}, {"b", "a"},
+		{"a", "c"}, {"c", "a"},
+		{"b", "c"}, {"c", "b"},
+	}
+
+	if reflect.DeepEqual(got, expected) == false {
+		t.Errorf("Got %v, expected %v", got, expected)
+	}
+
+	// With 4 bots in 4 player games, each bot should sit in each seat once...
+
+	got = tournament_seatings([]string{"a", "b", "c", "d"}, []int{4})
+
+	if len(got) != 4 {
+		t.Fatalf("Got %d seatings, expected 4", len(got))
+	}
+
+	for seat := 0; seat < 4; seat++ {
+		seen := make(map[string]bool)
+		for _, seating := range got {
+			seen[seating[seat]] = true
+		}
+		if len(seen) != 4 {
+			t.Errorf("Seat %d only had %v", seat, seen)
+		}
+	}
+}
+
+func TestGameKey(t *testing.T) {
+
+	// Commands can contain spaces, so splitting them differently must give different keys.
+
+	if game_key(1, 32, 32, []string{"a b", "c"}) == game_key(1, 32, 32, []string{"a", "b c"}) {
+		t.Errorf("Keys of differently split commands were the same")
+	}
+
+	if game_key(1, 32, 32, []string{"a", "b"}) == game_key(1, 32, 32, []string{"b", "a"}) {
+		t.Errorf("Keys of different seatings were the same")
+	}
+
+	if game_key(1, 32, 40, []string{"a", "b"}) == game_key(1, 40, 32, []string{"a", "b"}) {
+		t.Errorf("Keys of different sizes were the same")
+	}
+
+	result := &GameResult{Seed: 7, Width: 48, Height: 48, Players: []SeatResult{{Cmd: "x y", Seat: 0}, {Cmd: "z", Seat: 1}}}
+
+	if result.Key() != game_key(7, 48, 48, []string{"x y", "z"}) {
+		t.Errorf("GameResult.Key() was %q, expected %q", result.Key(), game_key(7, 48, 48, []string{"x y", "z"}))
+	}
+}
+
+func TestResultsStore(t *testing.T) {
+
+	store := filepath.Join(t.TempDir(), "store.jsonl")
+
+	results, err := load_results(store)
+	if results != nil || err != nil {
+		t.Errorf("Missing store: got %v, %v", results, err)
+	}
+
+	a := &GameResult{Seed: 1, Width: 32, Height: 32, Players: []SeatResult{{"a", 0, 1, 500}, {"b", 1, 2, 400}}}
+	b := &GameResult{Seed: 2, Width: 40, Height: 40, Players: []SeatResult{{"b", 0, 1, 900}, {"a", 1, 2, 0}}}
+
+	append_result(store, a)
+	append_result(store, b)
+
+	results, err = load_results(store)
+	if err != nil || len(results) != 2 || reflect.DeepEqual(results[0], a) == false || reflect.DeepEqual(results[1], b) == false {
+		t.Errorf("Got %v, %v", results, err)
+	}
+
+	// A line cut short is an error, not silently dropped...
+
+	contents, _ := ioutil.ReadFile(store)
+	ioutil.WriteFile(store, contents[:len(contents) - 10], 0644)
+
+	if _, err = load_results(store); err == nil {
+		t.Errorf("No error from a truncated store")
+	}
+}
+
+func TestTournamentResume(t *testing.T) {
+
+	dir := t.TempDir()
+	store := filepath.Join(dir, "store.jsonl")
+
+	args := []string{"--tournament", store, "--seeds", "1-2", "--sizes", "32", "--player-counts", "2", "--no-replay", "builtin:idle", "builtin:greedy"}
+
+	run_dubnium(t, dir, args...)
+
+	results, err := load_results(store)
+	if err != nil || len(results) != 4 {					// 2 seeds, 2 rotations
+		t.Fatalf("Got %d results, %v", len(results), err)
+	}
+
+	var keys []string
+	for _, result := range results {
+		keys = append(keys, result.Key())
+		if result.Players[0].Seat != 0 || result.Players[1].Seat != 1 || result.Width != 32 {
+			t.Errorf("Odd result %+v", result)
+		}
+	}
+
+	// Running again should play nothing...
+
+	run_dubnium(t, dir, args...)
+
+	if results, _ = load_results(store); len(results) != 4 {
+		t.Errorf("Rerun: got %d results, expected 4", len(results))
+	}
+
+	// And after losing the last game, should play just that one...
+
+	contents, _ := ioutil.ReadFile(store)
+	lines := strings.SplitAfter(strings.TrimSpace(string(contents)), "\n")
+	ioutil.WriteFile(store, []byte(strings.Join(lines[:3], "")), 0644)
+
+	run_dubnium(t, dir, args...)
+
+	results, _ = load_results(store)
+	if len(results) != 4 || results[3].Key() != keys[3] {
+		t.Fatalf("Resume: got %d results, expected the lost game %q back", len(results), keys[3])
+	}
+
+	resumed, _ := ioutil.ReadFile(store)
+	if strings.HasPrefix(string(resumed), strings.Join(lines[:3], "")) == false {
+		t.Errorf("The games already in the store were changed")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func recorded_game(t *testing.T, dir string, bots ...string) (*PrintedStats, string) {
+
+	// Plays a game with transcripts in dir, returning its results and the transcripts' base name.
+
+	args := append([]string{"--transcripts", "--seed", "5", "--width", "32", "--height", "32", "--replay-directory", dir}, bots...)
+	out := run_dubnium(t, dir, args...)
+
+	ps := new(PrintedStats)
+	if err := json.Unmarshal([]byte(out), ps); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "transcript-*-p0.in"))
+	if len(matches) != 1 {
+		t.Fatalf("Found transcripts %v", matches)
+	}
+
+	return ps, strings.TrimSuffix(matches[0], "-p0.in")
+}
+
+func TestTranscripts(t *testing.T) {
+
+	dir := t.TempDir()
+	ps, base := recorded_game(t, dir, "builtin:greedy", "builtin:random")
+
+	for _, pid := range []string{"0", "1"} {
+
+		// The .in file should be exactly the stream the bot was sent, i.e. what
+		// --bot-input rebuilds from the replay...
+
+		in, _ := ioutil.ReadFile(base + "-p" + pid + ".in")
+		expected := run_dubnium(t, dir, "--bot-input", ps.Replay, pid)
+
+		if string(in) != expected {
+			t.Errorf("Player %s's .in file differs from --bot-input's stream", pid)
+		}
+
+		// The .out file should be one JSON line per turn, starting with the bot's name at turn 0...
+
+		f, err := os.Open(base + "-p" + pid + ".out")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		turn := 0
+
+		for ; scanner.Scan(); turn++ {
+
+			var tl TranscriptLine
+			if err := json.Unmarshal(scanner.Bytes(), &tl); err != nil {
+				t.Fatalf("Player %s, line %d: %v", pid, turn, err)
+			}
+
+			if tl.Turn != turn || tl.Timeout {
+				t.Fatalf("Player %s, line %d: got %+v", pid, turn, tl)
+			}
+		}
+
+		f.Close()
+
+		if turn != turns_from_size(32, 32) + 1 {
+			t.Errorf("Player %s: got %d lines, expected %d", pid, turn, turns_from_size(32, 32) + 1)
+		}
+	}
+}
+
+func TestTranscriptWriter(t *testing.T) {
+
+	base := filepath.Join(t.TempDir(), "t")
+	transcript := NewTranscript(base, 3)
+
+	var bot bytes.Buffer
+
+	w := transcript.Wrap(&bot)
+	w.Write([]byte("pregame\n"))
+	transcript.Record(0, "MyBot")
+	w.Write([]byte("1\n"))
+	transcript.RecordTimeout(1)
+	transcript.Record(2, "m 0 n")
+	transcript.Close()
+
+	in, _ := ioutil.ReadFile(base + "-p3.in")
+	out, _ := ioutil.ReadFile(base + "-p3.out")
+
+	if string(in) != "pregame\n1\n" || bot.String() != "pregame\n1\n" {
+		t.Errorf("Got .in %q and bot stream %q", in, bot.String())
+	}
+
+	expected := `{"turn":0,"line":"MyBot"}` + "\n" + `{"turn":1,"line":"","timeout":true}` + "\n" + `{"turn":2,"line":"m 0 n"}` + "\n"
+
+	if string(out) != expected {
+		t.Errorf("Got .out:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	// A nil transcript, i.e. not recording, should do nothing...
+
+	var none *Transcript
+
+	if none.Wrap(&bot) != &bot {
+		t.Errorf("A nil transcript wrapped its writer")
+	}
+
+	none.Record(0, "x")
+	none.RecordTimeout(0)
+	none.Close()
+}
@@ -0,0 +1,267 @@
+package trueskill
+
+// TrueSkill for free-for-all games (every team is a single player), which is what
+// the Halite ladder used. The defaults are those of the Python trueskill package.
+//
+// The factor graph is the usual one: skill -> performance -> a chain of differences
+// between adjacent ranks, each with a win (or draw) truncation factor. Messages on
+// the chain are passed back and forth until they settle.
+//
+// See Herbrich, Minka & Graepel, "TrueSkill: A Bayesian Skill Rating System" (2006).
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	DEFAULT_MU = 25.0
+	DEFAULT_SIGMA = DEFAULT_MU / 3
+	DEFAULT_BETA = DEFAULT_SIGMA / 2
+	DEFAULT_TAU = DEFAULT_SIGMA / 100
+	DEFAULT_DRAW_PROBABILITY = 0.10
+
+	max_iterations = 10
+	min_delta = 0.0001
+)
+
+type Rating struct {
+	Mu							float64
+	Sigma						float64
+}
+
+func NewRating() Rating {
+	return Rating{DEFAULT_MU, DEFAULT_SIGMA}
+}
+
+func (self Rating) Exposure() float64 {				// The conservative estimate used for leaderboards
+	return self.Mu - 3 * self.Sigma
+}
+
+type Env struct {
+	Beta						float64
+	Tau							float64
+	DrawProbability				float64
+}
+
+func NewEnv() *Env {
+	return &Env{DEFAULT_BETA, DEFAULT_TAU, DEFAULT_DRAW_PROBABILITY}
+}
+
+// --------------------------------------------------------------------------------------
+
+type gaussian struct {				// In natural parameters; the zero value is the uniform "distribution"
+	pi							float64		// 1 / sigma^2
+	tau							float64		// mu / sigma^2
+}
+
+func from_mu_sigma(mu, sigma float64) gaussian {
+	pi := 1 / (sigma * sigma)
+	return gaussian{pi, pi * mu}
+}
+
+func (self gaussian) mu() float64 {
+	if self.pi == 0 {
+		return 0
+	}
+	return self.tau / self.pi
+}
+
+func (self gaussian) variance() float64 {
+	return 1 / self.pi
+}
+
+func (self gaussian) mul(other gaussian) gaussian {
+	return gaussian{self.pi + other.pi, self.tau + other.tau}
+}
+
+func (self gaussian) div(other gaussian) gaussian {
+	return gaussian{self.pi - other.pi, self.tau - other.tau}
+}
+
+func (self gaussian) delta(other gaussian) float64 {
+	return math.Max(math.Abs(self.tau - other.tau), math.Sqrt(math.Abs(self.pi - other.pi)))
+}
+
+func pdf(x float64) float64 {
+	return math.Exp(-x * x / 2) / math.Sqrt(2 * math.Pi)
+}
+
+func cdf(x float64) float64 {
+	return math.Erfc(-x / math.Sqrt2) / 2
+}
+
+func ppf(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2 * p - 1)
+}
+
+// The V and W functions of the truncated Gaussian, for wins and draws.
+
+func v_win(t, e float64) float64 {
+	denom := cdf(t - e)
+	if denom < 2.222758749e-162 {
+		return -t + e
+	}
+	return pdf(t - e) / denom
+}
+
+func w_win(t, e float64) float64 {
+	v := v_win(t, e)
+	return v * (v + t - e)
+}
+
+func v_draw(t, e float64) float64 {
+	abs_t := math.Abs(t)
+	denom := cdf(e - abs_t) - cdf(-e - abs_t)
+	if denom < 2.222758749e-162 {
+		if t < 0 {
+			return -t - e
+		}
+		return -t + e
+	}
+	v := (pdf(-e - abs_t) - pdf(e - abs_t)) / denom
+	if t < 0 {
+		return -v
+	}
+	return v
+}
+
+func w_draw(t, e float64) float64 {
+	abs_t := math.Abs(t)
+	denom := cdf(e - abs_t) - cdf(-e - abs_t)
+	if denom < 2.222758749e-162 {
+		return 1
+	}
+	v := v_draw(abs_t, e)
+	return v * v + ((e - abs_t) * pdf(e - abs_t) - (-e - abs_t) * pdf(-e - abs_t)) / denom
+}
+
+// --------------------------------------------------------------------------------------
+
+func (self *Env) Rate(ratings []Rating, ranks []int) []Rating {
+
+	// Lower rank is better; equal ranks are draws. Returns new ratings in the same order.
+
+	n := len(ratings)
+
+	if n < 2 {
+		return append([]Rating(nil), ratings...)
+	}
+
+	order := make([]int, n)				// Indices into ratings, best first
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ranks[order[a]] < ranks[order[b]]
+	})
+
+	draw_margin := ppf((self.DrawProbability + 1) / 2) * math.Sqrt(2) * self.Beta
+
+	// Skill priors and the performance messages they send down...
+
+	priors := make([]gaussian, n)
+	perf := make([]gaussian, n)			// The marginal of each performance variable
+	perf_down := make([]gaussian, n)	// The message each performance got from its skill
+
+	for i, idx := range order {
+		r := ratings[idx]
+		priors[i] = from_mu_sigma(r.Mu, math.Sqrt(r.Sigma * r.Sigma + self.Tau * self.Tau))
+		perf_down[i] = from_mu_sigma(priors[i].mu(), math.Sqrt(priors[i].variance() + self.Beta * self.Beta))
+		perf[i] = perf_down[i]
+	}
+
+	// The chain of differences d[k] = perf[k] - perf[k + 1]...
+
+	diff := make([]gaussian, n - 1)			// Marginals of the difference variables
+	diff_down := make([]gaussian, n - 1)	// Message from sum factor k to diff[k]
+	diff_trunc := make([]gaussian, n - 1)	// Message from truncation factor k to diff[k]
+	up_left := make([]gaussian, n - 1)		// Message from sum factor k to perf[k]
+	up_right := make([]gaussian, n - 1)		// Message from sum factor k to perf[k + 1]
+
+	send_down := func(k int) {
+		a := perf[k].div(up_left[k])
+		b := perf[k + 1].div(up_right[k])
+		msg := from_mu_sigma(a.mu() - b.mu(), math.Sqrt(a.variance() + b.variance()))
+		diff[k] = diff[k].div(diff_down[k]).mul(msg)
+		diff_down[k] = msg
+	}
+
+	truncate := func(k int) {
+		cavity := diff[k].div(diff_trunc[k])
+		sqrt_pi := math.Sqrt(cavity.pi)
+		t := cavity.tau / sqrt_pi
+		e := draw_margin * sqrt_pi
+		var v, w float64
+		if ranks[order[k]] == ranks[order[k + 1]] {
+			v, w = v_draw(t, e), w_draw(t, e)
+		} else {
+			v, w = v_win(t, e), w_win(t, e)
+		}
+		marginal := gaussian{cavity.pi / (1 - w), (cavity.tau + sqrt_pi * v) / (1 - w)}
+		diff_trunc[k] = marginal.div(cavity)
+		diff[k] = marginal
+	}
+
+	send_left := func(k int) {					// perf[k] = d[k] + perf[k + 1]
+		d := diff[k].div(diff_down[k])
+		b := perf[k + 1].div(up_right[k])
+		msg := from_mu_sigma(d.mu() + b.mu(), math.Sqrt(d.variance() + b.variance()))
+		perf[k] = perf[k].div(up_left[k]).mul(msg)
+		up_left[k] = msg
+	}
+
+	send_right := func(k int) {					// perf[k + 1] = perf[k] - d[k]
+		d := diff[k].div(diff_down[k])
+		a := perf[k].div(up_left[k])
+		msg := from_mu_sigma(a.mu() - d.mu(), math.Sqrt(a.variance() + d.variance()))
+		perf[k + 1] = perf[k + 1].div(up_right[k]).mul(msg)
+		up_right[k] = msg
+	}
+
+	if n == 2 {
+		send_down(0)
+		truncate(0)
+	} else {
+		for iteration := 0; iteration < max_iterations; iteration++ {
+
+			biggest := 0.0
+
+			for k := 0; k < n - 2; k++ {
+				before := diff[k]
+				send_down(k)
+				truncate(k)
+				biggest = math.Max(biggest, diff[k].delta(before))
+				send_right(k)
+			}
+
+			for k := n - 2; k > 0; k-- {
+				before := diff[k]
+				send_down(k)
+				truncate(k)
+				biggest = math.Max(biggest, diff[k].delta(before))
+				send_left(k)
+			}
+
+			if biggest <= min_delta {
+				break
+			}
+		}
+	}
+
+	send_left(0)
+	send_right(n - 2)
+
+	// And back up to the skills...
+
+	ret := make([]Rating, n)
+
+	for i, idx := range order {
+		cavity := perf[i].div(perf_down[i])
+		up := from_mu_sigma(cavity.mu(), math.Sqrt(cavity.variance() + self.Beta * self.Beta))
+		skill := priors[i].mul(up)
+		ret[idx] = Rating{skill.mu(), math.Sqrt(skill.variance())}
+	}
+
+	return ret
+}
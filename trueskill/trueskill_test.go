@@ -0,0 +1,64 @@
+package trueskill
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Expected values are from the Python trueskill package, with default settings.
+
+func check(t *testing.T, got []Rating, want []string) {
+	for n, r := range got {
+		s := fmt.Sprintf("%.3f %.3f", r.Mu, r.Sigma)
+		if s != want[n] {
+			t.Errorf("Rating %d: expected %v, got %v\n", n, want[n], s)
+		}
+	}
+}
+
+func TestOneVsOne(t *testing.T) {
+
+	got := NewEnv().Rate([]Rating{NewRating(), NewRating()}, []int{1, 2})
+
+	check(t, got, []string{
+		"29.396 7.171",
+		"20.604 7.171",
+	})
+}
+
+func TestOneVsOneDraw(t *testing.T) {
+
+	got := NewEnv().Rate([]Rating{NewRating(), NewRating()}, []int{1, 1})
+
+	check(t, got, []string{
+		"25.000 6.458",
+		"25.000 6.458",
+	})
+}
+
+func TestFreeForAll(t *testing.T) {
+
+	// Given out of order, to check the result comes back in the same order.
+
+	got := NewEnv().Rate([]Rating{NewRating(), NewRating(), NewRating()}, []int{3, 1, 2})
+
+	check(t, got, []string{
+		"18.325 6.656",
+		"31.675 6.656",
+		"25.000 6.208",
+	})
+}
+
+func TestFourPlayers(t *testing.T) {
+
+	// From Moserware's C# port (its FourTeamsOfOneNotDrawn test), which has the same defaults.
+
+	got := NewEnv().Rate([]Rating{NewRating(), NewRating(), NewRating(), NewRating()}, []int{1, 2, 3, 4})
+
+	check(t, got, []string{
+		"33.207 6.348",
+		"27.401 5.787",
+		"22.599 5.787",
+		"16.793 6.348",
+	})
+}